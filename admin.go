@@ -0,0 +1,41 @@
+package gomongoapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Returns the client of the admin routes, the admin client if one is set, otherwise the main client
+func (s *server) adminMongoClient() *mongo.Client {
+	if s.adminClient != nil {
+		return s.adminClient
+	}
+
+	return s.mongoClient
+}
+
+// Route to get the dbStats of the queried database, run with the admin client
+// /api/stats?database=app
+func (s *server) getStats(c *gin.Context) {
+
+	// Get database name, return error if one isn't available
+	dbName, ok := s.getDatabaseName(c)
+	if !ok {
+		return
+	}
+
+	var stats bson.D
+	err := s.adminMongoClient().Database(dbName).RunCommand(c.Request.Context(), bson.D{{Key: "dbStats", Value: 1}}).Decode(&stats)
+	if err != nil {
+		s.sendError(c, http.StatusInternalServerError, "Error getting database stats: %s", err.Error())
+		return
+	}
+
+	docs := []bson.D{stats}
+	s.encodeResults(docs)
+
+	s.sendResult(c, http.StatusOK, s.jsonValue(docs[0]))
+}
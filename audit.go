@@ -0,0 +1,81 @@
+package gomongoapi
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys the audit entry database and query are stored under while the request is handled
+const (
+	auditDatabaseKey = "gomongoapi.auditDatabase"
+	auditQueryKey    = "gomongoapi.auditQuery"
+)
+
+// AuditEntry describes an api request passed to the audit logger once it is handled
+type AuditEntry struct {
+	// Time the request was received
+	Time time.Time
+
+	// Client IP of the request
+	ClientIP string
+
+	// Identity of the client read from the audit identity context key, empty if unset
+	Identity string
+
+	// Database and collection of the request, empty if they weren't resolved
+	Database   string
+	Collection string
+
+	// Operation of the route, ex) find, aggregate, insert
+	Operation string
+
+	// Filter or pipeline sent by the client, nil if the route has none or the body couldn't be read
+	Query interface{}
+
+	// Status code of the response, rejected requests have their error status
+	Status int
+}
+
+// Returns middleware that passes an audit entry of every api request to the audit logger after it is handled,
+// including requests that were rejected
+func (s *server) auditMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		entry := AuditEntry{
+			Time:       start,
+			ClientIP:   ctx.ClientIP(),
+			Database:   ctx.GetString(auditDatabaseKey),
+			Collection: s.collectionParam(ctx),
+			Status:     ctx.Writer.Status(),
+		}
+
+		if ctx.FullPath() != "" {
+			entry.Operation = path.Base(ctx.FullPath())
+		}
+
+		if query, ok := ctx.Get(auditQueryKey); ok {
+			entry.Query = query
+		}
+
+		if s.auditIdentityKey != "" {
+			if identity, ok := ctx.Get(s.auditIdentityKey); ok {
+				entry.Identity = fmt.Sprint(identity)
+			}
+		}
+
+		s.auditLogger(entry)
+	}
+}
+
+// Records the filter or pipeline of the request for its audit entry
+func (s *server) setAuditQuery(ctx *gin.Context, query interface{}) {
+	if s.auditLogger != nil {
+		ctx.Set(auditQueryKey, query)
+	}
+}
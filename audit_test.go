@@ -0,0 +1,34 @@
+package gomongoapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuditRejectedByMiddleware(t *testing.T) {
+
+	var entries []AuditEntry
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetAuditLogger(func(entry AuditEntry) {
+		entries = append(entries, entry)
+	})
+	opts.SetRouter(gin.New())
+	s := NewServer(opts).(*server)
+
+	// Auth middleware rejecting every request, added before the routes are created like users do
+	s.SetAPIMiddleware(func(ctx *gin.Context) {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+	})
+	s.createRoutes()
+
+	rec := serveTest(s, http.MethodPost, "/api/collections/users/find", `{}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if len(entries) != 1 || entries[0].Status != http.StatusUnauthorized {
+		t.Fatalf("expected one audit entry of the rejected request, got %+v", entries)
+	}
+}
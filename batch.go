@@ -0,0 +1,199 @@
+package gomongoapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Default number of batch sub queries run at the same time
+const defaultBatchConcurrency = 4
+
+// Batch operations
+const (
+	batchFind  = "find"
+	batchCount = "count"
+)
+
+// Sub query of a batch request
+type batchQuery struct {
+	Collection string
+	Operation  string
+	Filter     bson.M
+	Limit      int64
+
+	coll       *mongo.Collection
+	projection bson.M
+}
+
+// Result of a batch sub query, either its documents, its count or its error
+type batchResult struct {
+	docs  []bson.D
+	count int64
+	err   error
+}
+
+// Runs several finds and counts in one request. /api/batch
+// Valid URL parameters are 'database' and 'readPreference'
+// Sub queries run in parallel, bounded by the batch concurrency, and results are returned in the same order.
+// A failed sub query has an Error instead of failing the whole batch.
+// Request body should be an array of sub queries, operation is find or count, limit defaults to the find limit
+//	ex) Request Body: [{"Collection": "users", "Operation": "find", "Filter": {"Active": true}, "Limit": 10}]
+func (s *server) batch(ctx *gin.Context) {
+
+	// Get database name, return error if one isn't available
+	dbName, ok := s.getDatabaseName(ctx)
+	if !ok {
+		return
+	}
+
+	var queries []batchQuery
+	err := ctx.ShouldBindJSON(&queries)
+	if err != nil {
+		s.sendError(ctx, http.StatusBadRequest, "Error reading body request: %s", err.Error())
+		return
+	}
+	s.setAuditQuery(ctx, queries)
+
+	if s.maxBatchSize != 0 && len(queries) > s.maxBatchSize {
+		s.sendError(ctx, http.StatusBadRequest, "Batch has %d queries, max is %d", len(queries), s.maxBatchSize)
+		return
+	}
+
+	// Check every sub query before running any of them
+	for i := range queries {
+		q := &queries[i]
+
+		if q.Operation != batchFind && q.Operation != batchCount {
+			s.sendError(ctx, http.StatusBadRequest, "Query %d operation must be find or count", i)
+			return
+		}
+		if q.Collection == "" {
+			s.sendError(ctx, http.StatusBadRequest, "Query %d collection name was not passed", i)
+			return
+		}
+		if !s.isCollectionAllowed(q.Collection) {
+			s.sendError(ctx, http.StatusForbidden, "Collection %s is not allowed", q.Collection)
+			return
+		}
+		if q.Limit < 0 {
+			s.sendError(ctx, http.StatusBadRequest, "Query %d limit can't be negative", i)
+			return
+		}
+		if q.Limit == 0 {
+			q.Limit, _ = strconv.ParseInt(s.findLimit, 10, 64)
+		}
+		if s.maxLimit != 0 && q.Limit > int64(s.maxLimit) {
+			s.sendError(ctx, http.StatusBadRequest, "Query %d limit is greater than max limit set by server", i)
+			return
+		}
+		if !s.checkFilter(ctx, q.Filter) {
+			return
+		}
+		if !s.checkRequireFilter(ctx, q.Collection, q.Filter) {
+			return
+		}
+
+		q.Filter, ok = s.scopeFilter(ctx, q.Collection, q.Filter)
+		if !ok {
+			return
+		}
+
+		q.Filter, ok = s.interceptFilter(ctx, dbName, q.Collection, q.Filter)
+		if !ok {
+			return
+		}
+
+		q.projection = s.excludeDefaultFields(q.Collection, bson.M{})
+
+		q.coll, ok = s.getCollection(ctx, dbName, q.Collection)
+		if !ok {
+			return
+		}
+	}
+
+	// Run the sub queries on a bounded pool of workers
+	concurrency := s.batchConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	results := make([]batchResult, len(queries))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range queries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = s.runBatchQuery(ctx.Request.Context(), &queries[i])
+		}(i)
+	}
+	wg.Wait()
+
+	s.sendResult(ctx, http.StatusOK, bson.M{"Results": s.batchResponse(ctx, queries, results)})
+}
+
+// Returns the response of each sub query, its error, count or documents encoded and transformed like find results
+func (s *server) batchResponse(ctx *gin.Context, queries []batchQuery, results []batchResult) []bson.M {
+
+	res := make([]bson.M, len(queries))
+	for i, r := range results {
+		switch {
+		case r.err != nil:
+			res[i] = bson.M{"Error": r.err.Error()}
+		case queries[i].Operation == batchCount:
+			res[i] = bson.M{"Count": r.count}
+		default:
+			docs := s.capResults(ctx, r.docs)
+			s.encodeResults(docs)
+			s.transformResults(ctx, docs)
+			res[i] = bson.M{"Documents": s.jsonDocuments(docs)}
+		}
+	}
+
+	return res
+}
+
+// Runs the batch sub query
+func (s *server) runBatchQuery(ctx context.Context, q *batchQuery) batchResult {
+
+	filter := q.Filter
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	if q.Operation == batchCount {
+		var count int64
+		err := s.withRetry(ctx, func() error {
+			var err error
+			count, err = q.coll.CountDocuments(ctx, filter)
+			return err
+		})
+		return batchResult{count: count, err: err}
+	}
+
+	opts := options.Find().SetLimit(q.Limit).SetAllowDiskUse(s.allowDiskUse)
+	if len(q.projection) > 0 {
+		opts.SetProjection(q.projection)
+	}
+	var cursor *mongo.Cursor
+	err := s.withRetry(ctx, func() error {
+		var err error
+		cursor, err = q.coll.Find(ctx, filter, opts)
+		return err
+	})
+	if err != nil {
+		return batchResult{err: err}
+	}
+
+	docs, _, err := s.readCursor(ctx, cursor)
+	return batchResult{docs: docs, err: err}
+}
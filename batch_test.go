@@ -0,0 +1,97 @@
+package gomongoapi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestBatchInterceptsFilters(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetFilterInterceptor(func(ctx *gin.Context, db, coll string, filter bson.M) (bson.M, error) {
+		if coll == "secrets" {
+			return nil, errors.New("secrets can't be queried")
+		}
+		return filter, nil
+	})
+	s := newTestServer(opts)
+
+	rec := serveTest(s, http.MethodPost, "/api/batch", `[{"Collection": "secrets", "Operation": "count", "Filter": {"a": 1}}]`)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
+	}
+}
+
+func TestBatchRequiresFilter(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetRequireFilterCollections([]string{"events"})
+	s := newTestServer(opts)
+
+	rec := serveTest(s, http.MethodPost, "/api/batch", `[{"Collection": "events", "Operation": "find"}]`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
+
+func TestExcludeDefaultFields(t *testing.T) {
+
+	s := &server{defaultExcludeFields: map[string][]string{"users": {"Password"}}}
+
+	if got := s.excludeDefaultFields("users", bson.M{}); got["Password"] != 0 {
+		t.Errorf("expected Password to be excluded, got %v", got)
+	}
+	if got := s.excludeDefaultFields("users", bson.M{"Name": 1}); len(got) != 1 {
+		t.Errorf("expected inclusion projection to be kept as is, got %v", got)
+	}
+	if got := s.excludeDefaultFields("orders", bson.M{}); len(got) != 0 {
+		t.Errorf("expected no excluded fields, got %v", got)
+	}
+}
+
+func TestBatchRejectsNegativeLimit(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	s := newTestServer(opts)
+
+	rec := serveTest(s, http.MethodPost, "/api/batch", `[{"Collection": "users", "Operation": "find", "Limit": -1}]`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
+
+func TestBatchResponseTransformsResults(t *testing.T) {
+
+	s := &server{resultTransformers: []ResultTransformer{func(ctx *gin.Context, doc bson.D) bson.D {
+		redacted := bson.D{}
+		for _, e := range doc {
+			if e.Key != "Password" {
+				redacted = append(redacted, e)
+			}
+		}
+		return redacted
+	}}}
+	ctx, _ := newTestContext("/api/batch")
+
+	queries := []batchQuery{{Operation: batchFind}, {Operation: batchCount}}
+	results := []batchResult{
+		{docs: []bson.D{{{Key: "Name", Value: "Jon"}, {Key: "Password", Value: "secret"}}}},
+		{count: 3},
+	}
+
+	res := s.batchResponse(ctx, queries, results)
+	docs := res[0]["Documents"].([]interface{})
+	if doc := docs[0].(map[string]interface{}); doc["Password"] != nil || doc["Name"] != "Jon" {
+		t.Errorf("expected password to be redacted, got %v", doc)
+	}
+	if res[1]["Count"] != int64(3) {
+		t.Errorf("expected count 3, got %v", res[1])
+	}
+}
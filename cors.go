@@ -0,0 +1,67 @@
+package gomongoapi
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Returns middleware that allows cross origin requests to paths under the base path from the origins, "*" allows any origin.
+// Preflight requests are answered by the middleware, from allowed origins with 204 and from others with 403.
+func corsMiddleware(basePath string, origins []string) gin.HandlerFunc {
+
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader("Origin")
+		if origin == "" || !underPath(ctx.Request.URL.Path, basePath) {
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("Vary", "Origin")
+		originAllowed := allowed["*"] || allowed[origin]
+		if originAllowed {
+			ctx.Header("Access-Control-Allow-Origin", origin)
+		}
+
+		if ctx.Request.Method != http.MethodOptions || ctx.GetHeader("Access-Control-Request-Method") == "" {
+			ctx.Next()
+			return
+		}
+
+		// Preflight request
+		if !originAllowed {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		ctx.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		if headers := ctx.GetHeader("Access-Control-Request-Headers"); headers != "" {
+			ctx.Header("Access-Control-Allow-Headers", headers)
+		}
+		ctx.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// Returns true if the path is the base path or under it
+func underPath(path, basePath string) bool {
+	return path == basePath || strings.HasPrefix(path, strings.TrimSuffix(basePath, "/")+"/")
+}
+
+// Installs the CORS middleware for paths under the base path if origins are set.
+// It is installed on the engine so preflight requests are answered even though no OPTIONS routes are registered,
+// engine middleware also runs before the not found and method not allowed handlers.
+// Groups copy the engine middleware when they are created, so it must be installed before the groups are.
+func installCORS(router *gin.Engine, basePath string, origins []string) {
+
+	if len(origins) == 0 {
+		return
+	}
+
+	router.Use(corsMiddleware(path.Join("/", basePath), origins))
+}
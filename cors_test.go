@@ -0,0 +1,106 @@
+package gomongoapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetAPICORS([]string{"https://grafana.example.com"})
+	s := newTestServer(opts)
+
+	// Preflight requests from allowed origins are answered with 204
+	req := httptest.NewRequest(http.MethodOptions, "/api/collections/users/find", nil)
+	req.Header.Set("Origin", "https://grafana.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected preflight status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://grafana.example.com" {
+		t.Errorf("expected allowed origin header, got %q", got)
+	}
+
+	// Preflight requests from other origins are rejected
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected preflight status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+
+	// Preflight requests outside the api group aren't answered
+	req = httptest.NewRequest(http.MethodOptions, "/custom/report", nil)
+	req.Header.Set("Origin", "https://grafana.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code == http.StatusNoContent {
+		t.Errorf("expected preflight outside the api group not to be answered")
+	}
+
+	// Unknown api paths are still not found
+	rec = serveTest(s, http.MethodGet, "/api/unknown", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for unknown path, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	// No OPTIONS routes are registered
+	for _, r := range s.Routes() {
+		if r.Method == http.MethodOptions {
+			t.Errorf("expected no OPTIONS routes, got %s", r.Path)
+		}
+	}
+}
+
+func TestCORSOnRoutes(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetAPICORS([]string{"https://grafana.example.com"})
+	opts.SetCustomCORS([]string{"*"})
+	s := newTestServer(opts)
+	s.AddCustomGET("/report", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	// Requests to built-in routes get the allowed origin, even when the handler rejects them
+	req := httptest.NewRequest(http.MethodPost, "/api/collections/users/find", strings.NewReader(`{`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Origin", "https://grafana.example.com")
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://grafana.example.com" {
+		t.Errorf("expected allowed origin header on api route, got %q (status %d)", got, rec.Code)
+	}
+
+	// Requests to custom routes get their own allowed origins
+	req = httptest.NewRequest(http.MethodGet, "/custom/report", nil)
+	req.Header.Set("Origin", "https://other.example.com")
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://other.example.com" {
+		t.Errorf("expected allowed origin header on custom route, got %q", got)
+	}
+
+	// Requests from other origins don't get the header
+	req = httptest.NewRequest(http.MethodPost, "/api/collections/users/find", strings.NewReader(`{`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no allowed origin header, got %q", got)
+	}
+}
@@ -0,0 +1,314 @@
+package gomongoapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Header holding the token of the next page of a paged aggregate
+const cursorTokenHeader = "X-Cursor-Token"
+
+// Header set when a timeout cut results short
+const partialResultsHeader = "X-Partial-Results"
+
+// Default time a paged aggregate cursor is kept without being read
+const defaultCursorTTL = 5 * time.Minute
+
+// Aggregate cursor kept open between pages
+type pagedCursor struct {
+	cursor     *mongo.Cursor
+	dbName     string
+	collName   string
+	tenant     bson.M
+	pageSize   int
+	lastAccess time.Time
+}
+
+// Change stream of a watch request, open while its events are sent
+type watchedStream struct {
+	cancel     context.CancelFunc
+	lastAccess time.Time
+}
+
+// Open aggregate cursors keyed by token and open change streams.
+// Cursors not accessed within the ttl are closed and change streams not sent to within it are canceled.
+type cursorStore struct {
+	mu      sync.Mutex
+	cursors map[string]*pagedCursor
+	streams map[*watchedStream]bool
+	ttl     time.Duration
+}
+
+// Creates an empty cursor store, the default ttl is used if ttl isn't positive
+func newCursorStore(ttl time.Duration) *cursorStore {
+	if ttl <= 0 {
+		ttl = defaultCursorTTL
+	}
+
+	return &cursorStore{
+		cursors: make(map[string]*pagedCursor),
+		streams: make(map[*watchedStream]bool),
+		ttl:     ttl,
+	}
+}
+
+// Stores the cursor and returns its token
+func (cs *cursorStore) put(cursor *pagedCursor) (string, error) {
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	cs.putToken(token, cursor)
+	return token, nil
+}
+
+// Stores the cursor under an existing token
+func (cs *cursorStore) putToken(token string, cursor *pagedCursor) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cursor.lastAccess = time.Now()
+	cs.cursors[token] = cursor
+}
+
+// Removes and returns the cursor of the token.
+// The cursor is removed while in use so a token can't be read by two requests at once.
+func (cs *cursorStore) take(token string) (*pagedCursor, bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cursor, ok := cs.cursors[token]
+	delete(cs.cursors, token)
+	return cursor, ok
+}
+
+// Registers a change stream, cancel stops the watch request using it.
+// The stream must be removed once the request ends.
+func (cs *cursorStore) addStream(cancel context.CancelFunc) *watchedStream {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	stream := &watchedStream{cancel: cancel, lastAccess: time.Now()}
+	cs.streams[stream] = true
+	return stream
+}
+
+// Records that the change stream was just sent to
+func (cs *cursorStore) touchStream(stream *watchedStream) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	stream.lastAccess = time.Now()
+}
+
+// Removes the change stream
+func (cs *cursorStore) removeStream(stream *watchedStream) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	delete(cs.streams, stream)
+}
+
+// Closes cursors that weren't accessed within the ttl and cancels change streams that weren't sent to within it,
+// every interval until ctx is done. Canceled change streams are closed by their watch request.
+func (cs *cursorStore) reap(ctx context.Context, interval time.Duration) {
+
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cs.mu.Lock()
+		var expired []*pagedCursor
+		for token, cursor := range cs.cursors {
+			if time.Since(cursor.lastAccess) > cs.ttl {
+				expired = append(expired, cursor)
+				delete(cs.cursors, token)
+			}
+		}
+		var expiredStreams []*watchedStream
+		for stream := range cs.streams {
+			if time.Since(stream.lastAccess) > cs.ttl {
+				expiredStreams = append(expiredStreams, stream)
+				delete(cs.streams, stream)
+			}
+		}
+		cs.mu.Unlock()
+
+		for _, cursor := range expired {
+			cursor.cursor.Close(context.Background())
+		}
+		for _, stream := range expiredStreams {
+			stream.cancel()
+		}
+	}
+}
+
+// Returns the 'batchSize' url param, 0 if it wasn't passed, and true.
+// If it isn't a positive int or is greater than the max cursor batch size an error response is sent and false is returned.
+func (s *server) getBatchSize(ctx *gin.Context) (int, bool) {
+
+	param, ok := ctx.GetQuery("batchSize")
+	if !ok {
+		return 0, true
+	}
+
+	batchSize, err := strconv.Atoi(param)
+	if err != nil || batchSize <= 0 {
+		s.sendError(ctx, http.StatusBadRequest, "Batch size must be a positive int")
+		return 0, false
+	}
+	if s.maxCursorBatchSize != 0 && batchSize > s.maxCursorBatchSize {
+		s.sendError(ctx, http.StatusBadRequest, "Batch size is greater than max batch size %d set by server", s.maxCursorBatchSize)
+		return 0, false
+	}
+
+	return batchSize, true
+}
+
+// Returns the 'pageSize' url param, 0 if it wasn't passed, and true.
+// If it isn't a positive int an error response is sent and false is returned.
+func (s *server) getPageSize(ctx *gin.Context) (int, bool) {
+
+	param, ok := ctx.GetQuery("pageSize")
+	if !ok {
+		return 0, true
+	}
+
+	pageSize, err := strconv.Atoi(param)
+	if err != nil || pageSize <= 0 {
+		s.sendError(ctx, http.StatusBadRequest, "Page size must be a positive int")
+		return 0, false
+	}
+
+	return pageSize, true
+}
+
+// Sends the next page of the cursor.
+// If the cursor isn't exhausted it is stored and its token is sent in the X-Cursor-Token header.
+func (s *server) sendPage(ctx *gin.Context, token string, cursor *pagedCursor) {
+
+	// Read the page
+	res := make([]bson.D, 0, cursor.pageSize)
+	for len(res) < cursor.pageSize && cursor.cursor.Next(ctx.Request.Context()) {
+		var doc bson.D
+		if err := cursor.cursor.Decode(&doc); err != nil {
+			cursor.cursor.Close(context.Background())
+			s.sendError(ctx, http.StatusInternalServerError, "Error decoding results: %s", err.Error())
+			return
+		}
+		res = append(res, doc)
+	}
+	if err := cursor.cursor.Err(); err != nil {
+		cursor.cursor.Close(context.Background())
+		s.sendError(ctx, http.StatusInternalServerError, "Error decoding results: %s", err.Error())
+		return
+	}
+
+	// Keep the cursor if there are more results
+	if cursor.cursor.RemainingBatchLength() > 0 || cursor.cursor.ID() != 0 {
+		var err error
+		if token == "" {
+			token, err = s.cursors.put(cursor)
+		} else {
+			s.cursors.putToken(token, cursor)
+		}
+		if err != nil {
+			cursor.cursor.Close(context.Background())
+			s.sendError(ctx, http.StatusInternalServerError, "Error creating cursor token: %s", err.Error())
+			return
+		}
+		ctx.Header(cursorTokenHeader, token)
+	} else {
+		cursor.cursor.Close(context.Background())
+	}
+
+	s.encodeResults(res)
+	s.transformResults(ctx, res)
+	s.sendDocuments(ctx, http.StatusOK, res)
+}
+
+// Sends the next page of the cursor of the token passed in the 'cursorToken' url param.
+// The token must belong to an aggregate on the same database and collection, run for the same tenant.
+func (s *server) sendNextPage(ctx *gin.Context, dbName, collName string) {
+
+	token := ctx.Query("cursorToken")
+	cursor, ok := s.cursors.take(token)
+	if !ok {
+		s.sendError(ctx, http.StatusNotFound, "Cursor token not found, it may have expired")
+		return
+	}
+
+	if cursor.dbName != dbName || cursor.collName != collName {
+		s.cursors.putToken(token, cursor)
+		s.sendError(ctx, http.StatusBadRequest, "Cursor token doesn't belong to this collection")
+		return
+	}
+
+	tenant, ok := s.resolveTenant(ctx)
+	if !ok {
+		s.cursors.putToken(token, cursor)
+		return
+	}
+	if !reflect.DeepEqual(cursor.tenant, tenant) {
+		s.cursors.putToken(token, cursor)
+		s.sendError(ctx, http.StatusForbidden, "Cursor token doesn't belong to this tenant")
+		return
+	}
+
+	s.sendPage(ctx, token, cursor)
+}
+
+// Decodes all documents of the cursor and closes it.
+// If the server returns partial results on timeout and the cursor is cut short by a timeout,
+// the documents read so far are returned with partial set to true.
+func (s *server) readCursor(ctx context.Context, cursor *mongo.Cursor) ([]bson.D, bool, error) {
+
+	defer cursor.Close(context.Background())
+
+	docs := []bson.D{}
+	for cursor.Next(ctx) {
+		var doc bson.D
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, false, err
+		}
+		docs = append(docs, doc)
+	}
+
+	if err := cursor.Err(); err != nil {
+		if s.returnPartialOnTimeout && isTimeoutError(err) {
+			return docs, true, nil
+		}
+		return nil, false, err
+	}
+
+	return docs, false, nil
+}
+
+// Returns true if the error is caused by a timeout or context deadline
+func isTimeoutError(err error) bool {
+	return mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,79 @@
+package gomongoapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestReapChangeStreams(t *testing.T) {
+
+	cs := newCursorStore(5 * time.Second)
+	idleCtx, cancelIdle := context.WithCancel(context.Background())
+	activeCtx, cancelActive := context.WithCancel(context.Background())
+	defer cancelActive()
+
+	idle := cs.addStream(cancelIdle)
+	active := cs.addStream(cancelActive)
+	idle.lastAccess = time.Now().Add(-10 * time.Second)
+
+	reapCtx, stopReap := context.WithCancel(context.Background())
+	defer stopReap()
+	go cs.reap(reapCtx, time.Second)
+
+	select {
+	case <-idleCtx.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected idle change stream to be canceled")
+	}
+
+	cs.touchStream(active)
+	if activeCtx.Err() != nil {
+		t.Error("expected active change stream to stay open")
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.streams[idle] || !cs.streams[active] {
+		t.Errorf("expected only the idle change stream to be removed")
+	}
+}
+
+func TestCursorTokenTenant(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetTenantFilter(func(ctx *gin.Context) bson.M {
+		return bson.M{"TenantID": ctx.GetHeader("X-Tenant")}
+	})
+	s := newTestServer(opts)
+
+	cursor, err := mongo.NewCursorFromDocuments([]interface{}{bson.D{{Key: "n", Value: 1}}}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := s.cursors.put(&pagedCursor{cursor: cursor, dbName: "app", collName: "users", tenant: bson.M{"TenantID": "a"}, pageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Resume requests with the token only succeed for the tenant the aggregate ran for
+	for _, tt := range []struct {
+		tenant string
+		code   int
+	}{{"b", http.StatusForbidden}, {"a", http.StatusOK}} {
+		req := httptest.NewRequest(http.MethodPost, "/api/collections/users/aggregate?cursorToken="+token, nil)
+		req.Header.Set("X-Tenant", tt.tenant)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("expected status %d for tenant %s, got %d: %s", tt.code, tt.tenant, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package gomongoapi
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Header holding the query the server ran, set on requests with 'debug=true'
+const debugQueryHeader = "X-Debug-Query"
+
+// Sets the debug query header to the query as extended JSON if debug queries are enabled and the request has 'debug=true'.
+// The query is the effective one, after the tenant filter, keyset condition and server limits are applied.
+func (s *server) setDebugQuery(ctx *gin.Context, query bson.M) {
+
+	if !s.debugQueries || ctx.Query("debug") != "true" {
+		return
+	}
+
+	b, err := bson.MarshalExtJSON(query, false, false)
+	if err != nil {
+		log.Printf("Error encoding debug query: %s\n", err.Error())
+		return
+	}
+
+	ctx.Header(debugQueryHeader, string(b))
+}
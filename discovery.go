@@ -0,0 +1,130 @@
+package gomongoapi
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Defaults for the discovery routes, kept small so they stay fast on large collections
+const (
+	defaultDiscoverySampleSize = 100
+	defaultDiscoveryTimeout    = 2 * time.Second
+)
+
+// Field found in the sampled documents of a collection
+type discoveredField struct {
+	Name  string
+	Types []string
+}
+
+// Returns the fields of a sample of the collection documents with their BSON types. /collections/:name/fields
+// Valid URL parameters are 'database' and 'readPreference'
+// Nested document fields are listed with dotted names, ex) Address.City
+// The sample size and timeout are the server discovery options.
+func (s *server) collectionFields(ctx *gin.Context) {
+
+	// Get database name, return error if one isn't available
+	dbName, ok := s.getDatabaseName(ctx)
+	if !ok {
+		return
+	}
+
+	// Get collection name, return error if one isn't passed or allowed
+	collName, ok := s.getCollectionName(ctx)
+	if !ok {
+		return
+	}
+
+	// Only sample documents of the tenant of the request
+	filter, ok := s.scopeFilter(ctx, collName, bson.M{})
+	if !ok {
+		return
+	}
+
+	// Let the filter interceptor rewrite or reject the filter
+	filter, ok = s.interceptFilter(ctx, dbName, collName, filter)
+	if !ok {
+		return
+	}
+
+	pipeLine := []bson.M{
+		{"$match": filter},
+		{"$sample": bson.M{"size": s.discoverySampleSize}},
+	}
+
+	// Get collection handle with the request read preference
+	coll, ok := s.getCollection(ctx, dbName, collName)
+	if !ok {
+		return
+	}
+
+	discoveryCtx, cancel := context.WithTimeout(ctx.Request.Context(), s.discoveryTimeout)
+	defer cancel()
+
+	var cursor *mongo.Cursor
+	err := s.withRetry(discoveryCtx, func() error {
+		var err error
+		cursor, err = coll.Aggregate(discoveryCtx, pipeLine)
+		return err
+	})
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error sampling documents: %s", err.Error())
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	fieldTypes := make(map[string]map[string]bool)
+	for cursor.Next(discoveryCtx) {
+		addFieldTypes(fieldTypes, "", cursor.Current)
+	}
+	if err := cursor.Err(); err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error sampling documents: %s", err.Error())
+		return
+	}
+
+	fields := make([]discoveredField, 0, len(fieldTypes))
+	for name, types := range fieldTypes {
+		field := discoveredField{Name: name}
+		for typ := range types {
+			field.Types = append(field.Types, typ)
+		}
+		sort.Strings(field.Types)
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+
+	res := bson.M{
+		"Fields": fields,
+	}
+
+	s.sendResult(ctx, http.StatusOK, res)
+}
+
+// Adds the types of the document fields to fieldTypes, nested document fields are added with dotted names
+func addFieldTypes(fieldTypes map[string]map[string]bool, prefix string, doc bson.Raw) {
+
+	elems, err := doc.Elements()
+	if err != nil {
+		return
+	}
+
+	for _, elem := range elems {
+		name := joinPath(prefix, elem.Key())
+		value := elem.Value()
+
+		if fieldTypes[name] == nil {
+			fieldTypes[name] = make(map[string]bool)
+		}
+		fieldTypes[name][value.Type.String()] = true
+
+		if nested, ok := value.DocumentOK(); ok {
+			addFieldTypes(fieldTypes, name, nested)
+		}
+	}
+}
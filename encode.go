@@ -0,0 +1,353 @@
+package gomongoapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Layout of dates converted to the output timezone, ex) 2023-04-01T12:00:00.000+02:00
+const outputTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// TimestampFormat is how BSON timestamps are represented in results
+type TimestampFormat string
+
+const (
+	// TimestampRaw keeps the timestamp as is, {"T": <seconds>, "I": <increment>}
+	TimestampRaw TimestampFormat = "raw"
+
+	// TimestampEpochSeconds converts the timestamp to its epoch seconds
+	TimestampEpochSeconds TimestampFormat = "epochSeconds"
+
+	// TimestampDate converts the timestamp to a UTC date, formatted in the output location if one is set
+	TimestampDate TimestampFormat = "date"
+)
+
+// IDHandling is how the _id field of result documents is handled
+type IDHandling string
+
+const (
+	// IDKeep keeps the _id field as is
+	IDKeep IDHandling = "keep"
+
+	// IDHexString converts an ObjectID _id field to its 24 character hex string
+	IDHexString IDHandling = "hexString"
+
+	// IDOmit removes the _id field
+	IDOmit IDHandling = "omit"
+)
+
+// NonFiniteFloatHandling is how NaN and infinite floats in results are represented, JSON can't encode them
+type NonFiniteFloatHandling string
+
+const (
+	// NonFiniteNull converts NaN and infinite floats to null
+	NonFiniteNull NonFiniteFloatHandling = "null"
+
+	// NonFiniteString converts NaN and infinite floats to the strings "NaN", "Infinity" and "-Infinity"
+	NonFiniteString NonFiniteFloatHandling = "string"
+)
+
+// BinaryHandling is how BSON binary values in results are represented
+type BinaryHandling string
+
+const (
+	// BinaryRaw keeps binary values as is, {"Subtype": <subtype>, "Data": <base64>}
+	BinaryRaw BinaryHandling = "raw"
+
+	// BinaryString converts UUID binaries to their canonical string, ex) 123e4567-e89b-12d3-a456-426614174000,
+	// and other binaries to their base64 string
+	BinaryString BinaryHandling = "string"
+)
+
+// Converts the values of the result documents in place before they are sent
+func (s *server) encodeResults(docs []bson.D) {
+	for i := range docs {
+		docs[i] = s.encodeID(docs[i])
+		s.encodeDocument(docs[i])
+	}
+}
+
+// Converts the values and keys of the document in place, its _id field is left as is
+func (s *server) encodeDocument(doc bson.D) {
+	s.encodeValue(doc)
+	if s.keyReplacer != nil {
+		replaceKeys(doc, s.keyReplacer)
+	}
+}
+
+// Converts the change event in place before it is sent.
+// The event _id is its resume token so it is kept as is, id handling is applied to the full document instead.
+func (s *server) encodeChangeEvent(event bson.D) {
+	for i, elem := range event {
+		if doc, ok := elem.Value.(bson.D); ok && elem.Key == "fullDocument" {
+			event[i].Value = s.encodeID(doc)
+		}
+	}
+	s.encodeDocument(event)
+}
+
+// Returns the replacer of the key replacement pairs, nil if there are none
+func newKeyReplacer(pairs []string) *strings.Replacer {
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	return strings.NewReplacer(pairs...)
+}
+
+// Replaces characters in the keys of the value and its nested documents in place
+func replaceKeys(value interface{}, replacer *strings.Replacer) {
+
+	switch v := value.(type) {
+	case primitive.D:
+		for i := range v {
+			v[i].Key = replacer.Replace(v[i].Key)
+			replaceKeys(v[i].Value, replacer)
+		}
+	case map[string]interface{}:
+		replaceMapKeys(v, replacer)
+	case primitive.M:
+		replaceMapKeys(v, replacer)
+	case primitive.A:
+		for _, elem := range v {
+			replaceKeys(elem, replacer)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			replaceKeys(elem, replacer)
+		}
+	}
+}
+
+// Replaces characters in the keys of the map and its nested documents in place
+func replaceMapKeys(m map[string]interface{}, replacer *strings.Replacer) {
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	for _, key := range keys {
+		elem := m[key]
+		replaceKeys(elem, replacer)
+		if replaced := replacer.Replace(key); replaced != key {
+			delete(m, key)
+			m[replaced] = elem
+		}
+	}
+}
+
+// ResultTransformer transforms a result document of the request before it is sent, ex) to redact or rename fields
+type ResultTransformer func(ctx *gin.Context, doc bson.D) bson.D
+
+// Applies the result transformers in the order they were added to each result document in place
+func (s *server) transformResults(ctx *gin.Context, docs []bson.D) {
+	for _, transformer := range s.resultTransformers {
+		for i := range docs {
+			docs[i] = transformer(ctx, docs[i])
+		}
+	}
+}
+
+// Returns the document with the server id handling applied to its _id field
+func (s *server) encodeID(doc bson.D) bson.D {
+
+	for i, elem := range doc {
+		if elem.Key != "_id" {
+			continue
+		}
+
+		switch s.idHandling {
+		case IDHexString:
+			if id, ok := elem.Value.(primitive.ObjectID); ok {
+				doc[i].Value = id.Hex()
+			}
+		case IDOmit:
+			return append(doc[:i], doc[i+1:]...)
+		}
+		break
+	}
+
+	return doc
+}
+
+// Returns the converted value, documents and arrays are converted recursively in place
+func (s *server) encodeValue(value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = s.encodeValue(elem)
+		}
+	case primitive.M:
+		for key, elem := range v {
+			v[key] = s.encodeValue(elem)
+		}
+	case primitive.D:
+		for i := range v {
+			v[i].Value = s.encodeValue(v[i].Value)
+		}
+	case primitive.A:
+		for i := range v {
+			v[i] = s.encodeValue(v[i])
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = s.encodeValue(v[i])
+		}
+	case primitive.Timestamp:
+		return s.encodeTimestamp(v)
+	case primitive.DateTime:
+		if s.outputLocation != nil {
+			return v.Time().In(s.outputLocation).Format(outputTimeLayout)
+		}
+	case primitive.Binary:
+		if s.binaryHandling == BinaryString {
+			return encodeBinary(v)
+		}
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return s.encodeNonFinite(v)
+		}
+	}
+
+	return value
+}
+
+// Returns the NaN or infinite float in the server non finite float handling
+func (s *server) encodeNonFinite(f float64) interface{} {
+
+	if s.nonFiniteFloatHandling != NonFiniteString {
+		return nil
+	}
+
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case f > 0:
+		return "Infinity"
+	}
+
+	return "-Infinity"
+}
+
+// Returns the binary as its canonical UUID string if it is a UUID, otherwise as its base64 string
+func encodeBinary(b primitive.Binary) string {
+
+	if b.Subtype == bsontype.BinaryUUID && len(b.Data) == 16 {
+		h := hex.EncodeToString(b.Data)
+		return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+	}
+
+	return base64.StdEncoding.EncodeToString(b.Data)
+}
+
+// Returns the timestamp in the server timestamp format.
+// Dates are formatted in the output location like DateTime values if one is set.
+func (s *server) encodeTimestamp(ts primitive.Timestamp) interface{} {
+
+	switch s.timestampFormat {
+	case TimestampEpochSeconds:
+		return ts.T
+	case TimestampDate:
+		date := time.Unix(int64(ts.T), 0).UTC()
+		if s.outputLocation != nil {
+			return date.In(s.outputLocation).Format(outputTimeLayout)
+		}
+		return date
+	}
+
+	return ts
+}
+
+// Returns the documents as values ready to be marshaled to JSON
+func (s *server) jsonDocuments(docs []bson.D) []interface{} {
+
+	res := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		res[i] = s.jsonValue(doc)
+	}
+
+	return res
+}
+
+// Returns the value ready to be marshaled to JSON.
+// Documents become maps, or ordered documents that keep their field order if the server preserves field order.
+func (s *server) jsonValue(value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case primitive.D:
+		if s.preserveFieldOrder {
+			doc := make(orderedDocument, len(v))
+			for i, elem := range v {
+				doc[i] = primitive.E{Key: elem.Key, Value: s.jsonValue(elem.Value)}
+			}
+			return doc
+		}
+
+		doc := make(map[string]interface{}, len(v))
+		for _, elem := range v {
+			doc[elem.Key] = s.jsonValue(elem.Value)
+		}
+		return doc
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = s.jsonValue(elem)
+		}
+	case primitive.M:
+		for key, elem := range v {
+			v[key] = s.jsonValue(elem)
+		}
+	case primitive.A:
+		for i := range v {
+			v[i] = s.jsonValue(v[i])
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = s.jsonValue(v[i])
+		}
+	}
+
+	return value
+}
+
+// Document that marshals to a JSON object with its fields in order
+type orderedDocument []primitive.E
+
+// Marshals the document to a JSON object keeping the field order
+func (d orderedDocument) MarshalJSON() ([]byte, error) {
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, elem := range d {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(elem.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(elem.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
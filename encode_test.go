@@ -0,0 +1,138 @@
+package gomongoapi
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEncodeNonFinite(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		handling NonFiniteFloatHandling
+		value    float64
+		want     string
+	}{
+		{"NaN null", NonFiniteNull, math.NaN(), `null`},
+		{"+Inf null", NonFiniteNull, math.Inf(1), `null`},
+		{"-Inf null", NonFiniteNull, math.Inf(-1), `null`},
+		{"NaN string", NonFiniteString, math.NaN(), `"NaN"`},
+		{"+Inf string", NonFiniteString, math.Inf(1), `"Infinity"`},
+		{"-Inf string", NonFiniteString, math.Inf(-1), `"-Infinity"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &server{nonFiniteFloatHandling: tt.handling}
+			docs := []bson.D{{
+				{Key: "value", Value: tt.value},
+				{Key: "nested", Value: bson.D{{Key: "value", Value: tt.value}}},
+				{Key: "array", Value: bson.A{tt.value}},
+			}}
+
+			s.encodeResults(docs)
+			out, err := json.Marshal(s.jsonDocuments(docs))
+			if err != nil {
+				t.Fatalf("error marshaling results: %s", err)
+			}
+
+			want := `[{"array":[` + tt.want + `],"nested":{"value":` + tt.want + `},"value":` + tt.want + `}]`
+			if string(out) != want {
+				t.Errorf("expected %s, got %s", want, out)
+			}
+
+			// Sending the results must not fail once they are encoded
+			rec := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(rec)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+			s.sendDocuments(ctx, http.StatusOK, docs)
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestEncodeBinary(t *testing.T) {
+
+	uuid := []byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+
+	tests := []struct {
+		name  string
+		value primitive.Binary
+		want  string
+	}{
+		{"uuid", primitive.Binary{Subtype: bsontype.BinaryUUID, Data: uuid}, "123e4567-e89b-12d3-a456-426614174000"},
+		{"generic", primitive.Binary{Subtype: bsontype.BinaryGeneric, Data: []byte("hello")}, "aGVsbG8="},
+		{"generic 16 bytes", primitive.Binary{Subtype: bsontype.BinaryGeneric, Data: uuid}, "Ej5FZ+ibEtOkVkJmFBdAAA=="},
+		{"uuid wrong length", primitive.Binary{Subtype: bsontype.BinaryUUID, Data: []byte("hello")}, "aGVsbG8="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeBinary(tt.value); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+
+			// Binaries are only converted with the string binary handling
+			s := &server{binaryHandling: BinaryString}
+			if got := s.encodeValue(tt.value); got != tt.want {
+				t.Errorf("expected %s with string binary handling, got %v", tt.want, got)
+			}
+			s.binaryHandling = BinaryRaw
+			if _, ok := s.encodeValue(tt.value).(primitive.Binary); !ok {
+				t.Errorf("expected binary to be kept with raw binary handling")
+			}
+		})
+	}
+}
+
+func TestEncodeChangeEvent(t *testing.T) {
+
+	s := &server{idHandling: IDOmit}
+	id := primitive.NewObjectID()
+	event := bson.D{
+		{Key: "_id", Value: bson.D{{Key: "_data", Value: "8264"}}},
+		{Key: "operationType", Value: "insert"},
+		{Key: "fullDocument", Value: bson.D{{Key: "_id", Value: id}, {Key: "Name", Value: "Jon"}}},
+	}
+
+	s.encodeChangeEvent(event)
+	if token, ok := lookupField(event, "_id._data"); !ok || token != "8264" {
+		t.Errorf("expected resume token to be kept, got %v", event)
+	}
+	if _, ok := lookupField(event, "fullDocument._id"); ok {
+		t.Errorf("expected full document _id to be omitted, got %v", event)
+	}
+}
+
+func TestEncodeTimestampOutputLocation(t *testing.T) {
+
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	s := &server{timestampFormat: TimestampDate, outputLocation: loc}
+	date := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	doc := bson.D{
+		{Key: "ts", Value: primitive.Timestamp{T: uint32(date.Unix())}},
+		{Key: "date", Value: primitive.NewDateTimeFromTime(date)},
+	}
+	s.encodeDocument(doc)
+
+	if doc[0].Value != doc[1].Value {
+		t.Errorf("expected timestamp %v to be formatted like date %v", doc[0].Value, doc[1].Value)
+	}
+
+	s.outputLocation = nil
+	if got := s.encodeTimestamp(primitive.Timestamp{T: uint32(date.Unix())}); got != date {
+		t.Errorf("expected UTC date %v, got %v", date, got)
+	}
+}
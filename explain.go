@@ -0,0 +1,138 @@
+package gomongoapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Default max time the explain checking the documents a find scans may take
+const defaultScanCheckTimeout = time.Second
+
+// Returns true if the find doesn't scan the whole collection, or collection scans aren't rejected by the server.
+// The find is explained with the query planner verbosity, which costs an extra round trip but doesn't run it.
+// If the winning plan has a COLLSCAN stage a bad request response is sent and false is returned.
+func (s *server) checkCollectionScan(ctx *gin.Context, coll *mongo.Collection, filter bson.M, sort bson.D, limit int, skip int64) bool {
+
+	if !s.rejectCollectionScans {
+		return true
+	}
+
+	res, err := explainFind(ctx, coll, filter, sort, limit, skip, "queryPlanner", 0)
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error running explain: %s", err.Error())
+		return false
+	}
+
+	planner, _ := res["queryPlanner"].(bson.M)
+	if hasStage(planner["winningPlan"], "COLLSCAN") {
+		s.sendError(ctx, http.StatusBadRequest, "Query would scan the whole collection, collection scans are rejected by server")
+		return false
+	}
+
+	return true
+}
+
+// Returns true if the find scans no more than the max documents scanned, or the server has no max.
+// Mongo can't cap scanned documents, so this is approximated by explaining the find with the execution stats verbosity,
+// which runs its plan, bounded by the scan check timeout. Finds whose explain passes the timeout are treated as over the max.
+// If the find is over the max a bad request response is sent and false is returned.
+func (s *server) checkDocsScanned(ctx *gin.Context, coll *mongo.Collection, filter bson.M, sort bson.D, limit int, skip int64) bool {
+
+	if s.maxDocsScanned <= 0 {
+		return true
+	}
+
+	timeout := s.scanCheckTimeout
+	if timeout <= 0 {
+		timeout = defaultScanCheckTimeout
+	}
+
+	res, err := explainFind(ctx, coll, filter, sort, limit, skip, "executionStats", timeout)
+	if err != nil {
+		if isTimeoutError(err) {
+			s.sendError(ctx, http.StatusBadRequest, "Query scans too many documents to check within %s, max documents scanned is %d", timeout, s.maxDocsScanned)
+			return false
+		}
+
+		s.sendError(ctx, http.StatusInternalServerError, "Error running explain: %s", err.Error())
+		return false
+	}
+
+	stats, _ := res["executionStats"].(bson.M)
+	examined, ok := stats["totalDocsExamined"]
+	if !ok {
+		return true
+	}
+
+	var docsExamined int64
+	switch v := examined.(type) {
+	case int32:
+		docsExamined = int64(v)
+	case int64:
+		docsExamined = v
+	case float64:
+		docsExamined = int64(v)
+	}
+	if docsExamined > s.maxDocsScanned {
+		s.sendErrorDetails(ctx, http.StatusBadRequest, "Query scans more documents than max documents scanned set by server", bson.M{
+			"DocsExamined":   docsExamined,
+			"MaxDocsScanned": s.maxDocsScanned,
+		})
+		return false
+	}
+
+	return true
+}
+
+// Runs explain on the find with the verbosity, bounded by maxTime if it is positive, and returns the result
+func explainFind(ctx *gin.Context, coll *mongo.Collection, filter bson.M, sort bson.D, limit int, skip int64, verbosity string, maxTime time.Duration) (bson.M, error) {
+
+	find := bson.D{{Key: "find", Value: coll.Name()}, {Key: "filter", Value: filter}}
+	if len(sort) > 0 {
+		find = append(find, bson.E{Key: "sort", Value: sort})
+	}
+	if limit > 0 {
+		find = append(find, bson.E{Key: "limit", Value: limit})
+	}
+	if skip > 0 {
+		find = append(find, bson.E{Key: "skip", Value: skip})
+	}
+
+	cmd := bson.D{{Key: "explain", Value: find}, {Key: "verbosity", Value: verbosity}}
+	if maxTime > 0 {
+		cmd = append(cmd, bson.E{Key: "maxTimeMS", Value: maxTime.Milliseconds()})
+	}
+
+	var res bson.M
+	err := coll.Database().RunCommand(ctx.Request.Context(), cmd).Decode(&res)
+
+	return res, err
+}
+
+// Returns true if the plan or any of its nested plans, like input stages and shard plans, is the stage
+func hasStage(plan interface{}, stage string) bool {
+
+	switch v := plan.(type) {
+	case bson.M:
+		if v["stage"] == stage {
+			return true
+		}
+		for _, value := range v {
+			if hasStage(value, stage) {
+				return true
+			}
+		}
+	case bson.A:
+		for _, value := range v {
+			if hasStage(value, stage) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,137 @@
+package gomongoapi
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Trailers holding the number of documents in the export, counted before streaming, and the error that stopped it
+const (
+	exportCountTrailer = "X-Export-Count"
+	exportErrorTrailer = "X-Export-Error"
+)
+
+// Documents fetched per round trip and written between flushes by the export route
+const (
+	exportBatchSize  = 1000
+	exportFlushLines = 1000
+)
+
+// Streams every document of the collection as newline delimited JSON. /collections/:name/export
+// Valid URL parameters are 'database' and 'readPreference'
+// Requires exports enabled. Documents are read from the cursor as they are written, so a slow client slows reading
+// from mongo instead of buffering the collection. The X-Export-Count trailer has the number of documents counted before
+// streaming, and the X-Export-Error trailer has the error that stopped the export if it is partial.
+// Documents are scoped to the collection base filter and the tenant of the request.
+func (s *server) collectionExport(ctx *gin.Context) {
+
+	if !s.exportEnabled {
+		s.sendError(ctx, http.StatusForbidden, "Export route is not enabled on this server")
+		return
+	}
+
+	// Get database name, return error if one isn't available
+	dbName, ok := s.getDatabaseName(ctx)
+	if !ok {
+		return
+	}
+
+	// Get collection name, return error if one isn't passed or allowed
+	collName, ok := s.getCollectionName(ctx)
+	if !ok {
+		return
+	}
+
+	// Scope the export to the collection base filter and the tenant of the request
+	filter, ok := s.scopeFilter(ctx, collName, bson.M{})
+	if !ok {
+		return
+	}
+
+	// Let the filter interceptor rewrite or reject the filter
+	filter, ok = s.interceptFilter(ctx, dbName, collName, filter)
+	if !ok {
+		return
+	}
+
+	// Get collection handle with the request read preference
+	coll, ok := s.getCollection(ctx, dbName, collName)
+	if !ok {
+		return
+	}
+
+	// Resolve the count first, the estimated count reads collection metadata and is used when there is no filter
+	var count int64
+	err := s.withRetry(ctx.Request.Context(), func() error {
+		var err error
+		if len(filter) == 0 {
+			count, err = coll.EstimatedDocumentCount(ctx.Request.Context())
+		} else {
+			count, err = coll.CountDocuments(ctx.Request.Context(), filter)
+		}
+		return err
+	})
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error running export count: %s", err.Error())
+		return
+	}
+
+	opts := options.Find().SetBatchSize(exportBatchSize)
+	var cursor *mongo.Cursor
+	err = s.withRetry(ctx.Request.Context(), func() error {
+		var err error
+		cursor, err = coll.Find(ctx.Request.Context(), filter, opts)
+		return err
+	})
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error running export: %s", err.Error())
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	setDownloadHeader(ctx)
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Header("Trailer", exportCountTrailer+", "+exportErrorTrailer)
+	ctx.Status(http.StatusOK)
+
+	// Writes block while the client isn't reading, which stops reading from the cursor
+	encoder := json.NewEncoder(ctx.Writer)
+	written := 0
+	var exportErr error
+	for cursor.Next(ctx.Request.Context()) {
+		var doc bson.D
+		if err := cursor.Decode(&doc); err != nil {
+			exportErr = err
+			break
+		}
+		docs := []bson.D{doc}
+		s.encodeResults(docs)
+		s.transformResults(ctx, docs)
+		if err := encoder.Encode(s.jsonValue(docs[0])); err != nil {
+			return
+		}
+		written++
+
+		if written%exportFlushLines == 0 {
+			ctx.Writer.Flush()
+		}
+	}
+	if err := cursor.Err(); err != nil && exportErr == nil {
+		exportErr = err
+	}
+
+	// Trailers are sent after the body
+	ctx.Writer.Header().Set(exportCountTrailer, strconv.FormatInt(count, 10))
+	if exportErr != nil {
+		log.Printf("Error exporting collection %s after %d documents: %s\n", collName, written, exportErr.Error())
+		ctx.Writer.Header().Set(exportErrorTrailer, exportErr.Error())
+	}
+}
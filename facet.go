@@ -0,0 +1,66 @@
+package gomongoapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Returns true if a stage of the pipeline is a $facet
+func hasFacetStage(pipeLine []interface{}) bool {
+
+	for _, stage := range pipeLine {
+		var stageDoc map[string]interface{}
+		switch v := stage.(type) {
+		case map[string]interface{}:
+			stageDoc = v
+		case bson.M:
+			stageDoc = v
+		}
+
+		if _, ok := stageDoc["$facet"]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sends the facets of a $facet result document as separate named results.
+// With the 'facet' url param only the documents of that facet are sent, in the output format of the request,
+// otherwise every facet is sent as [{"Facet": name, "Documents": [...]}...] in the order of the document.
+func (s *server) sendFacets(ctx *gin.Context, code int, doc bson.D) {
+
+	name, single := ctx.GetQuery("facet")
+
+	res := make([]bson.M, 0, len(doc))
+	for _, elem := range doc {
+		values, ok := elem.Value.(bson.A)
+		if !ok {
+			s.sendError(ctx, http.StatusBadRequest, "Result field %s is not a facet", elem.Key)
+			return
+		}
+
+		docs := make([]bson.D, 0, len(values))
+		for _, value := range values {
+			if d, ok := value.(bson.D); ok {
+				docs = append(docs, d)
+			}
+		}
+
+		if single && elem.Key == name {
+			s.sendDocuments(ctx, code, docs)
+			return
+		}
+
+		res = append(res, bson.M{"Facet": elem.Key, "Documents": s.jsonDocuments(s.capResults(ctx, docs))})
+	}
+
+	if single {
+		s.sendError(ctx, http.StatusBadRequest, "Result has no facet %s", name)
+		return
+	}
+
+	s.sendResult(ctx, code, res)
+}
@@ -0,0 +1,449 @@
+package gomongoapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Returns true if the filter passes the server filter validation, otherwise sends a bad request response and returns false
+func (s *server) checkFilter(ctx *gin.Context, filter bson.M) bool {
+
+	if s.maxFilterDepth != 0 {
+		if depth := filterDepth(filter); depth > s.maxFilterDepth {
+			s.sendError(ctx, http.StatusBadRequest, "Filter depth %d is greater than max depth %d set by server", depth, s.maxFilterDepth)
+			return false
+		}
+	}
+
+	if s.disallowRegex {
+		if path, ok := findOperator(filter, "", "$regex", "$where"); ok {
+			s.sendError(ctx, http.StatusBadRequest, "Invalid filter: %s is not allowed by server", path)
+			return false
+		}
+	}
+
+	if s.validateOperators {
+		if err := validateOperators(filter, ""); err != nil {
+			s.sendError(ctx, http.StatusBadRequest, "Invalid filter: %s", err.Error())
+			return false
+		}
+	}
+
+	if s.maxInArraySize != 0 {
+		if path, size, ok := findLargeInArray(filter, "", s.maxInArraySize); ok {
+			s.sendError(ctx, http.StatusBadRequest, "Filter %s has %d values, more than max %d set by server", path, size, s.maxInArraySize)
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns true if the filter is not empty or the collection doesn't require one, otherwise sends a bad request response and returns false
+func (s *server) checkRequireFilter(ctx *gin.Context, collName string, filter bson.M) bool {
+
+	if len(filter) == 0 && (s.requireFilter || s.requireFilterCollections[collName]) {
+		s.sendError(ctx, http.StatusBadRequest, "A filter is required for collection %s, provide criteria to match", collName)
+		return false
+	}
+
+	return true
+}
+
+// Returns the filter scoped to the collection base filter and the tenant of the request and true.
+// The base filter of the collection and the tenant filter, if set, are ANDed with the passed one.
+// If the tenant filter returns nil the tenant can't be resolved, a forbidden response is sent and false is returned.
+func (s *server) scopeFilter(ctx *gin.Context, collName string, filter bson.M) (bson.M, bool) {
+
+	var scopes bson.A
+	if len(filter) > 0 {
+		scopes = append(scopes, filter)
+	}
+
+	if base, ok := s.baseFilters[collName]; ok {
+		scopes = append(scopes, base)
+	}
+
+	tenant, ok := s.resolveTenant(ctx)
+	if !ok {
+		return nil, false
+	}
+	if tenant != nil {
+		scopes = append(scopes, tenant)
+	}
+
+	switch len(scopes) {
+	case 0:
+		return filter, true
+	case 1:
+		return scopes[0].(bson.M), true
+	}
+
+	return bson.M{"$and": scopes}, true
+}
+
+// Returns the filter of the tenant of the request and true, nil if requests aren't scoped to a tenant.
+// If the tenant filter returns nil the tenant can't be resolved, a forbidden response is sent and false is returned.
+func (s *server) resolveTenant(ctx *gin.Context) (bson.M, bool) {
+
+	if s.tenantFilter == nil {
+		return nil, true
+	}
+
+	tenant := s.tenantFilter(ctx)
+	if tenant == nil {
+		s.sendError(ctx, http.StatusForbidden, "Tenant could not be resolved for the request")
+		return nil, false
+	}
+
+	return tenant, true
+}
+
+// Returns the fields of the tenant of the request that written documents are stamped with and true, nil if requests aren't scoped to a tenant.
+// The tenant filter must only have field equalities to be written. If the tenant can't be resolved or
+// its filter has operators a forbidden response is sent and false is returned.
+func (s *server) tenantFields(ctx *gin.Context) (bson.M, bool) {
+
+	tenant, ok := s.resolveTenant(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	for field, value := range tenant {
+		if strings.HasPrefix(field, "$") || hasOperatorKey(value) {
+			s.sendError(ctx, http.StatusForbidden, "Writes are not allowed, the tenant filter has operators")
+			return nil, false
+		}
+	}
+
+	return tenant, true
+}
+
+// Returns true if the value is a document with an operator key, ex) {"$in": [1, 2]}
+func hasOperatorKey(value interface{}) bool {
+
+	var doc map[string]interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		doc = v
+	case bson.M:
+		doc = v
+	}
+
+	for key := range doc {
+		if strings.HasPrefix(key, "$") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the first field of the tenant changed by the update operators, empty if the update doesn't change the tenant.
+// Parents and nested fields of the tenant fields count as changing them, as do $rename targets.
+func updatedTenantField(update bson.M, tenant bson.M) string {
+
+	for op, arg := range update {
+		var fields map[string]interface{}
+		switch v := arg.(type) {
+		case map[string]interface{}:
+			fields = v
+		case bson.M:
+			fields = v
+		}
+
+		for field, value := range fields {
+			paths := []string{field}
+			if target, ok := value.(string); ok && op == "$rename" {
+				paths = append(paths, target)
+			}
+
+			for _, path := range paths {
+				for tenantField := range tenant {
+					if path == tenantField || strings.HasPrefix(path, tenantField+".") || strings.HasPrefix(tenantField, path+".") {
+						return tenantField
+					}
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// Returns true if the collection has a base filter or requests are scoped to a tenant
+func (s *server) isScoped(collName string) bool {
+	_, ok := s.baseFilters[collName]
+	return ok || s.tenantFilter != nil
+}
+
+// Returns the filter returned by the filter interceptor and true, or the passed filter if none is set.
+// If the interceptor returns an error a forbidden response is sent and false is returned.
+func (s *server) interceptFilter(ctx *gin.Context, dbName, collName string, filter bson.M) (bson.M, bool) {
+
+	if s.filterInterceptor == nil {
+		return filter, true
+	}
+
+	filter, err := s.filterInterceptor(ctx, dbName, collName, filter)
+	if err != nil {
+		s.sendError(ctx, http.StatusForbidden, "Filter was rejected: %s", err.Error())
+		return nil, false
+	}
+
+	return filter, true
+}
+
+// Returns the nesting depth of the value, a document or array counts as one level
+func filterDepth(value interface{}) int {
+
+	maxDepth := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, elem := range v {
+			if depth := filterDepth(elem); depth > maxDepth {
+				maxDepth = depth
+			}
+		}
+	case bson.M:
+		return filterDepth(map[string]interface{}(v))
+	case []interface{}:
+		for _, elem := range v {
+			if depth := filterDepth(elem); depth > maxDepth {
+				maxDepth = depth
+			}
+		}
+	default:
+		return 0
+	}
+
+	return maxDepth + 1
+}
+
+// Returns the path of the first use of one of the operators in the value and true, or false if none are used
+func findOperator(value interface{}, path string, operators ...string) (string, bool) {
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			keyPath := joinPath(path, key)
+			for _, op := range operators {
+				if key == op {
+					return keyPath, true
+				}
+			}
+			if found, ok := findOperator(elem, keyPath, operators...); ok {
+				return found, true
+			}
+		}
+	case bson.M:
+		return findOperator(map[string]interface{}(v), path, operators...)
+	case []interface{}:
+		for i, elem := range v {
+			if found, ok := findOperator(elem, fmt.Sprintf("%s.%d", path, i), operators...); ok {
+				return found, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Returns the path and size of the first $in or $nin array in the value with more than max values and true,
+// or false if there are none
+func findLargeInArray(value interface{}, path string, max int) (string, int, bool) {
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			keyPath := joinPath(path, key)
+			if key == "$in" || key == "$nin" {
+				switch values := elem.(type) {
+				case []interface{}:
+					if len(values) > max {
+						return keyPath, len(values), true
+					}
+				case bson.A:
+					if len(values) > max {
+						return keyPath, len(values), true
+					}
+				}
+			}
+			if found, size, ok := findLargeInArray(elem, keyPath, max); ok {
+				return found, size, true
+			}
+		}
+	case bson.M:
+		return findLargeInArray(map[string]interface{}(v), path, max)
+	case []interface{}:
+		for i, elem := range v {
+			if found, size, ok := findLargeInArray(elem, fmt.Sprintf("%s.%d", path, i), max); ok {
+				return found, size, true
+			}
+		}
+	case bson.A:
+		return findLargeInArray([]interface{}(v), path, max)
+	}
+
+	return "", 0, false
+}
+
+// Checks that common query operators in the filter have sensibly typed arguments.
+// Returned errors describe the path of the offending operator, ex) age.$gt
+func validateOperators(filter map[string]interface{}, path string) error {
+
+	for key, value := range filter {
+		keyPath := joinPath(path, key)
+
+		// Top level operators
+		if strings.HasPrefix(key, "$") {
+			if err := validateOperator(key, value, keyPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Field with an operator document, ex) {"age": {"$gt": 5}}
+		ops, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for op, arg := range ops {
+			if !strings.HasPrefix(op, "$") {
+				continue
+			}
+			if err := validateOperator(op, arg, joinPath(keyPath, op)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Checks the argument of a single operator
+func validateOperator(op string, arg interface{}, path string) error {
+
+	switch op {
+	case "$gt", "$gte", "$lt", "$lte":
+		switch arg.(type) {
+		case map[string]interface{}, []interface{}, nil:
+			return operatorError(path, "a number, string or date", arg)
+		}
+	case "$in", "$nin", "$all":
+		if _, ok := arg.([]interface{}); !ok {
+			return operatorError(path, "an array", arg)
+		}
+	case "$exists":
+		switch arg.(type) {
+		case bool, float64:
+		default:
+			return operatorError(path, "a bool", arg)
+		}
+	case "$regex", "$options":
+		if _, ok := arg.(string); !ok {
+			return operatorError(path, "a string", arg)
+		}
+	case "$size":
+		size, ok := arg.(float64)
+		if !ok || size < 0 || size != float64(int64(size)) {
+			return operatorError(path, "a non negative integer", arg)
+		}
+	case "$type":
+		switch arg.(type) {
+		case string, float64, []interface{}:
+		default:
+			return operatorError(path, "a type name, number or array of them", arg)
+		}
+	case "$mod":
+		values, ok := arg.([]interface{})
+		if !ok || len(values) != 2 {
+			return operatorError(path, "an array of divisor and remainder", arg)
+		}
+		for _, v := range values {
+			if _, ok := v.(float64); !ok {
+				return operatorError(path, "an array of divisor and remainder", arg)
+			}
+		}
+	case "$and", "$or", "$nor":
+		clauses, ok := arg.([]interface{})
+		if !ok || len(clauses) == 0 {
+			return operatorError(path, "a non empty array of filters", arg)
+		}
+		for i, clause := range clauses {
+			doc, ok := clause.(map[string]interface{})
+			if !ok {
+				return operatorError(fmt.Sprintf("%s.%d", path, i), "a filter document", clause)
+			}
+			if err := validateOperators(doc, fmt.Sprintf("%s.%d", path, i)); err != nil {
+				return err
+			}
+		}
+	case "$not":
+		switch v := arg.(type) {
+		case map[string]interface{}:
+			return validateOperatorDoc(v, path)
+		case string:
+		default:
+			return operatorError(path, "an operator document or regex", arg)
+		}
+	case "$elemMatch":
+		doc, ok := arg.(map[string]interface{})
+		if !ok {
+			return operatorError(path, "a document", arg)
+		}
+		return validateOperators(doc, path)
+	}
+
+	return nil
+}
+
+// Checks an operator document, ex) the argument of $not
+func validateOperatorDoc(ops map[string]interface{}, path string) error {
+	for op, arg := range ops {
+		if err := validateOperator(op, arg, joinPath(path, op)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns an error describing the expected argument of the operator at path
+func operatorError(path, expected string, arg interface{}) error {
+	return fmt.Errorf("%s expects %s, got %s", path, expected, jsonTypeName(arg))
+}
+
+// Returns the JSON type name of a decoded value
+func jsonTypeName(value interface{}) string {
+
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+// Returns the dotted path of key under path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,141 @@
+package gomongoapi
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFilterInterceptorRoutes(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetExportEnabled(true)
+	opts.SetFilterInterceptor(func(ctx *gin.Context, db, coll string, filter bson.M) (bson.M, error) {
+		if coll == "secrets" {
+			return nil, errors.New("secrets can't be queried")
+		}
+		return filter, nil
+	})
+	opts.SetRouter(gin.New())
+	s := NewServer(opts).(*server)
+	if err := s.AddVariableRoute("secretNames", "secrets", "Name", nil); err != nil {
+		t.Fatal(err)
+	}
+	s.createRoutes()
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{"aggregate", http.MethodPost, "/api/collections/secrets/aggregate", `{"Aggregate": [{"$match": {"a": 1}}]}`},
+		{"groupCount", http.MethodPost, "/api/collections/secrets/groupCount?field=a", `{"a": 1}`},
+		{"distinct", http.MethodPost, "/api/collections/secrets/distinct?field=a", `{"a": 1}`},
+		{"timeseries", http.MethodPost, "/api/collections/secrets/timeseries?timeField=t&interval=1h&agg=count", `{"a": 1}`},
+		{"export", http.MethodGet, "/api/collections/secrets/export", ""},
+		{"fields", http.MethodGet, "/api/collections/secrets/fields", ""},
+		{"watch", http.MethodGet, "/api/collections/secrets/watch", ""},
+		{"variable", http.MethodGet, "/api/variables/secretNames", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveTest(s, tt.method, tt.target, tt.body)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestFilterInterceptorWatch(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetFilterInterceptor(func(ctx *gin.Context, db, coll string, filter bson.M) (bson.M, error) {
+		return bson.M{"Region": "eu"}, nil
+	})
+	s := newTestServer(opts)
+
+	// A filter added by the interceptor can't be applied to change events
+	rec := serveTest(s, http.MethodGet, "/api/collections/users/watch", "")
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
+	}
+}
+
+func TestFilterInterceptorAggregateMatch(t *testing.T) {
+
+	var intercepted bson.M
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetFilterInterceptor(func(ctx *gin.Context, db, coll string, filter bson.M) (bson.M, error) {
+		intercepted = filter
+		return nil, errors.New("rejected")
+	})
+	s := newTestServer(opts)
+
+	rec := serveTest(s, http.MethodPost, "/api/collections/users/aggregate", `{"Aggregate": [{"$match": {"a": 1}}, {"$match": {"b": 2}}, {"$limit": 1}]}`)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
+	}
+
+	want := bson.M{"$and": bson.A{bson.M{"a": float64(1)}, bson.M{"b": float64(2)}}}
+	if !reflect.DeepEqual(intercepted, want) {
+		t.Errorf("expected leading $match filters %v, got %v", want, intercepted)
+	}
+}
+
+func TestLeadingMatch(t *testing.T) {
+
+	pipeLine := []interface{}{
+		map[string]interface{}{"$match": map[string]interface{}{"a": 1}},
+		map[string]interface{}{"$sort": map[string]interface{}{"a": 1}},
+		map[string]interface{}{"$match": map[string]interface{}{"b": 1}},
+	}
+
+	filter, rest := leadingMatch(pipeLine)
+	if !reflect.DeepEqual(filter, bson.M{"a": 1}) {
+		t.Errorf("expected the first $match filter, got %v", filter)
+	}
+	if len(rest) != 2 {
+		t.Errorf("expected the stages after the leading $match, got %v", rest)
+	}
+
+	filter, rest = leadingMatch(pipeLine[1:])
+	if len(filter) != 0 || len(rest) != 2 {
+		t.Errorf("expected an empty filter and every stage, got %v and %v", filter, rest)
+	}
+}
+
+func TestRequireFilterRoutes(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetRequireFilterCollections([]string{"events"})
+	s := newTestServer(opts)
+
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"groupCount", "/api/collections/events/groupCount?field=a"},
+		{"distinct", "/api/collections/events/distinct?field=a"},
+		{"timeseries", "/api/collections/events/timeseries?timeField=t&interval=1h&agg=count"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveTest(s, http.MethodPost, tt.target, `{}`)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package gomongoapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GeoJSON geometry types
+var geometryTypes = map[string]bool{
+	"Point":              true,
+	"MultiPoint":         true,
+	"LineString":         true,
+	"MultiLineString":    true,
+	"Polygon":            true,
+	"MultiPolygon":       true,
+	"GeometryCollection": true,
+}
+
+// Sends the documents as a GeoJSON FeatureCollection.
+// The GeoJSON field of the collection is each feature geometry, the other top level fields are its properties.
+// If no GeoJSON field is set for the collection, or a document has no valid geometry, a bad request response is sent.
+func (s *server) sendGeoJSON(ctx *gin.Context, code int, docs []bson.D) {
+
+	collName := s.collectionParam(ctx)
+	field, ok := s.geoJSONFields[collName]
+	if !ok {
+		s.sendError(ctx, http.StatusBadRequest, "No GeoJSON field is set for collection %s", collName)
+		return
+	}
+
+	features := make([]bson.M, len(docs))
+	for i, doc := range docs {
+		geometry, ok := lookupField(doc, field)
+		if !ok || !isGeometry(geometry) {
+			s.sendError(ctx, http.StatusBadRequest, "Document %d has no valid GeoJSON geometry in field %s", i, field)
+			return
+		}
+
+		properties := make(bson.D, 0, len(doc))
+		for _, elem := range doc {
+			if elem.Key != field {
+				properties = append(properties, elem)
+			}
+		}
+
+		features[i] = bson.M{
+			"type":       "Feature",
+			"geometry":   s.jsonValue(geometry),
+			"properties": s.jsonValue(properties),
+		}
+	}
+
+	ctx.Header("Content-Type", "application/geo+json")
+	ctx.JSON(code, bson.M{
+		"type":     "FeatureCollection",
+		"features": features,
+	})
+}
+
+// Returns true if the value is a GeoJSON geometry, a document with a geometry type and its coordinates or geometries
+func isGeometry(value interface{}) bool {
+
+	doc, ok := value.(bson.D)
+	if !ok {
+		return false
+	}
+
+	typ, _ := doc.Map()["type"].(string)
+	if !geometryTypes[typ] {
+		return false
+	}
+
+	if typ == "GeometryCollection" {
+		_, ok = doc.Map()["geometries"].(bson.A)
+		return ok
+	}
+
+	_, ok = doc.Map()["coordinates"].(bson.A)
+	return ok
+}
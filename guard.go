@@ -0,0 +1,80 @@
+package gomongoapi
+
+import (
+	"net/http"
+	"path"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Returns middleware that rejects requests with a query string longer than the max length with 414,
+// or with more params than the max params with 400. A max of 0 means no limit.
+func (s *server) queryStringGuard() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		rawQuery := ctx.Request.URL.RawQuery
+
+		if s.maxQueryStringLength != 0 && len(rawQuery) > s.maxQueryStringLength {
+			s.sendError(ctx, http.StatusRequestURITooLong, "Query string length %d is greater than max length %d set by server", len(rawQuery), s.maxQueryStringLength)
+			ctx.Abort()
+			return
+		}
+
+		if s.maxQueryParams != 0 {
+			count := 0
+			for _, values := range ctx.Request.URL.Query() {
+				count += len(values)
+			}
+			if count > s.maxQueryParams {
+				s.sendError(ctx, http.StatusBadRequest, "Query param count %d is greater than max count %d set by server", count, s.maxQueryParams)
+				ctx.Abort()
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// Returns true if the request body is JSON or the server doesn't require it,
+// otherwise sends an unsupported media type response and returns false
+func (s *server) checkJSONContentType(ctx *gin.Context) bool {
+
+	if !s.requireJSONContentType || ctx.ContentType() == gin.MIMEJSON {
+		return true
+	}
+
+	s.sendError(ctx, http.StatusUnsupportedMediaType, "Content-Type must be %s", gin.MIMEJSON)
+	return false
+}
+
+// Returns middleware that rejects requests with url params the routes don't read with 400, listing the unknown params.
+// When query param filters are enabled, params with a type hint on the collection are also known on find and count.
+func (s *server) strictParamsGuard() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		operation := path.Base(ctx.FullPath())
+		filterParams := s.queryParamFilters && (operation == "find" || operation == "count")
+		typeHints := s.paramTypeHints[s.collectionParam(ctx)]
+
+		var unknown []string
+		for param := range ctx.Request.URL.Query() {
+			if reservedParams[param] {
+				continue
+			}
+			if _, ok := typeHints[param]; ok && filterParams {
+				continue
+			}
+			unknown = append(unknown, param)
+		}
+
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			s.sendErrorDetails(ctx, http.StatusBadRequest, "Unknown query params", bson.M{"UnknownParams": unknown})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package gomongoapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Result of the last mongo health check
+type healthStatus struct {
+	mu        sync.Mutex
+	checked   bool
+	err       error
+	lastCheck time.Time
+	latency   time.Duration
+}
+
+// Pings mongo and stores the result as the health status
+func (s *server) checkHealth(ctx context.Context) {
+
+	start := time.Now()
+	err := s.mongoClient.Ping(ctx, readpref.Primary())
+	latency := time.Since(start)
+
+	s.health.mu.Lock()
+	s.health.checked = true
+	s.health.err = err
+	s.health.lastCheck = start
+	s.health.latency = latency
+	s.health.mu.Unlock()
+}
+
+// Checks the health of mongo every interval until the context is done, the first check runs right away
+func (s *server) runHealthChecks(ctx context.Context, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		s.checkHealth(checkCtx)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Route to get the mongo connectivity, returns 200 if the last ping succeeded, otherwise 503.
+// With a health check interval the status cached by the background checker is returned, otherwise mongo is pinged.
+// /health
+//	ex) Result: {"Status": "ok", "LastCheck": "2023-04-01T10:00:00Z", "LatencyMs": 1.234}
+func (s *server) getHealth(c *gin.Context) {
+
+	if s.healthCheckInterval <= 0 {
+		s.checkHealth(c.Request.Context())
+	}
+
+	s.health.mu.Lock()
+	checked, err, lastCheck, latency := s.health.checked, s.health.err, s.health.lastCheck, s.health.latency
+	s.health.mu.Unlock()
+
+	if !checked {
+		c.JSON(http.StatusServiceUnavailable, bson.M{"Status": "unknown"})
+		return
+	}
+
+	res := bson.M{
+		"Status":    "ok",
+		"LastCheck": lastCheck.UTC(),
+		"LatencyMs": float64(latency.Microseconds()) / 1000,
+	}
+	code := http.StatusOK
+	if err != nil {
+		res["Status"] = "unavailable"
+		res["Error"] = err.Error()
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, res)
+}
@@ -0,0 +1,183 @@
+package gomongoapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SelectLanguage is the expression language of the 'select' url param
+type SelectLanguage string
+
+const (
+	// SelectNone disables the 'select' url param
+	SelectNone SelectLanguage = "none"
+
+	// SelectJSONPath evaluates the 'select' url param as JSONPath.
+	// Supported are the root $, child fields .name and ['name'], array indexes [0] and [-1] and wildcards .* and [*].
+	SelectJSONPath SelectLanguage = "jsonpath"
+)
+
+// Kinds of JSONPath segments
+const (
+	segmentField = iota
+	segmentIndex
+	segmentWildcard
+)
+
+// Segment of a parsed JSONPath expression
+type pathSegment struct {
+	kind  int
+	field string
+	index int
+}
+
+// Sends the values the 'select' expression picks out of the result documents, as a JSON array
+func (s *server) sendSelected(ctx *gin.Context, code int, docs []bson.D, expr string) {
+
+	if s.selectLanguage != SelectJSONPath {
+		s.sendError(ctx, http.StatusBadRequest, "Select is not enabled on this server")
+		return
+	}
+
+	segments, err := parseJSONPath(expr)
+	if err != nil {
+		s.sendError(ctx, http.StatusBadRequest, "Invalid select expression: %s", err.Error())
+		return
+	}
+
+	// Round trip through JSON so the expression sees the documents as they are sent
+	b, err := json.Marshal(s.jsonDocuments(docs))
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error encoding results: %s", err.Error())
+		return
+	}
+	var root interface{}
+	if err = json.Unmarshal(b, &root); err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error encoding results: %s", err.Error())
+		return
+	}
+
+	s.sendResult(ctx, code, evalJSONPath(root, segments))
+}
+
+// Returns the segments of the JSONPath expression, ex) $[*].Address.City
+func parseJSONPath(expr string) ([]pathSegment, error) {
+
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("expression must start with $")
+	}
+
+	var segments []pathSegment
+	rest := expr[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+
+			switch name {
+			case "":
+				return nil, fmt.Errorf("empty field name in %s", expr)
+			case "*":
+				segments = append(segments, pathSegment{kind: segmentWildcard})
+			default:
+				segments = append(segments, pathSegment{kind: segmentField, field: name})
+			}
+		case '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed [ in %s", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				segments = append(segments, pathSegment{kind: segmentWildcard})
+			case len(inner) >= 2 && inner[0] == '\'' && inner[len(inner)-1] == '\'':
+				segments = append(segments, pathSegment{kind: segmentField, field: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index [%s] in %s", inner, expr)
+				}
+				segments = append(segments, pathSegment{kind: segmentIndex, index: index})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in %s", rest[0], expr)
+		}
+	}
+
+	return segments, nil
+}
+
+// Returns the values the segments pick out of the value.
+// Without wildcards a single match is returned as is, otherwise the matches are returned as an array.
+func evalJSONPath(value interface{}, segments []pathSegment) interface{} {
+
+	matches := []interface{}{value}
+	wildcard := false
+	for _, segment := range segments {
+		var next []interface{}
+		for _, match := range matches {
+			switch segment.kind {
+			case segmentField:
+				if doc, ok := match.(map[string]interface{}); ok {
+					if v, ok := doc[segment.field]; ok {
+						next = append(next, v)
+					}
+				}
+			case segmentIndex:
+				if arr, ok := match.([]interface{}); ok {
+					index := segment.index
+					if index < 0 {
+						index += len(arr)
+					}
+					if index >= 0 && index < len(arr) {
+						next = append(next, arr[index])
+					}
+				}
+			case segmentWildcard:
+				wildcard = true
+				switch v := match.(type) {
+				case []interface{}:
+					next = append(next, v...)
+				case map[string]interface{}:
+					keys := make([]string, 0, len(v))
+					for key := range v {
+						keys = append(keys, key)
+					}
+					sort.Strings(keys)
+					for _, key := range keys {
+						next = append(next, v[key])
+					}
+				}
+			}
+		}
+		matches = next
+	}
+
+	if !wildcard {
+		if len(matches) == 0 {
+			return nil
+		}
+		return matches[0]
+	}
+
+	if matches == nil {
+		return []interface{}{}
+	}
+	return matches
+}
@@ -0,0 +1,168 @@
+package gomongoapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Header holding the sort key values of the last document, pass it as the 'after' url param to get the next page
+const nextAfterHeader = "X-Next-After"
+
+// Returns the sort and the keyset condition to use for a find and true.
+// Keyset paging is only used when the 'after' url param is passed, otherwise the sort is returned as is.
+// Paged sorts end with _id so documents with the same sort key values have a stable order, it is added if the sort doesn't have it.
+// The 'after' url param is the JSON array of the sort field values of the last seen document, ending with its _id,
+// ex) [42, {"$oid": "..."}]. Results continue after it, comparing with $gt on ascending fields and $lt on descending ones.
+// An empty 'after' requests the first page. If no sort is set results are sorted by _id.
+// If the param is invalid an error response is sent and false is returned.
+func (s *server) getAfter(ctx *gin.Context, sort bson.D) (bson.D, bson.M, bool) {
+
+	param, ok := ctx.GetQuery("after")
+	if !ok {
+		return sort, nil, true
+	}
+	if len(sort) == 0 {
+		sort = bson.D{{Key: "_id", Value: 1}}
+	}
+	sort = withIDSort(sort)
+	if param == "" {
+		return sort, nil, true
+	}
+
+	var doc struct {
+		After bson.A
+	}
+	err := bson.UnmarshalExtJSON([]byte(`{"after": `+param+`}`), false, &doc)
+	if err != nil {
+		s.sendError(ctx, http.StatusBadRequest, "After is not a valid JSON array: %s", err.Error())
+		return nil, nil, false
+	}
+	if len(doc.After) != len(sort) {
+		s.sendError(ctx, http.StatusBadRequest, "After must have %d values, one per sort field ending with _id", len(sort))
+		return nil, nil, false
+	}
+
+	return sort, keysetCondition(sort, doc.After), true
+}
+
+// Returns the sort with _id added as its last field, in the direction of the field before it.
+// Empty sorts and sorts that already have _id are returned as is.
+func withIDSort(sort bson.D) bson.D {
+
+	if len(sort) == 0 {
+		return sort
+	}
+	for _, elem := range sort {
+		if elem.Key == "_id" {
+			return sort
+		}
+	}
+
+	direction := 1
+	if isDescending(sort[len(sort)-1].Value) {
+		direction = -1
+	}
+
+	return append(sort[:len(sort):len(sort)], bson.E{Key: "_id", Value: direction})
+}
+
+// Returns the condition matching documents after the values in the sort order.
+// A document is after them if its first field is past the first value, or its first field is equal and the rest are after
+// the rest of the values, ex) {"$or": [{"a": {"$gt": 1}}, {"a": 1, "_id": {"$gt": <id>}}]}
+func keysetCondition(sort bson.D, values bson.A) bson.M {
+
+	branches := make(bson.A, len(sort))
+	for i, elem := range sort {
+		branch := bson.M{}
+		for j := 0; j < i; j++ {
+			branch[sort[j].Key] = values[j]
+		}
+
+		operator := "$gt"
+		if isDescending(elem.Value) {
+			operator = "$lt"
+		}
+		branch[elem.Key] = bson.M{operator: values[i]}
+		branches[i] = branch
+	}
+
+	if len(branches) == 1 {
+		return branches[0].(bson.M)
+	}
+
+	return bson.M{"$or": branches}
+}
+
+// Sets the next after header to the sort field values of the last document if keyset paging is used.
+// Nothing is set if the 'after' url param isn't passed, there is no sort or the last document doesn't have one of
+// the fields, ex) it is projected out.
+func (s *server) setNextAfter(ctx *gin.Context, sort bson.D, docs []bson.D) {
+
+	if _, ok := ctx.GetQuery("after"); !ok {
+		return
+	}
+	if len(sort) == 0 || len(docs) == 0 {
+		return
+	}
+
+	values := make(bson.A, len(sort))
+	for i, elem := range sort {
+		value, ok := lookupField(docs[len(docs)-1], elem.Key)
+		if !ok {
+			return
+		}
+		values[i] = value
+	}
+
+	// Marshal the values as extended JSON so types like ObjectID survive the round trip
+	b, err := bson.MarshalExtJSON(bson.D{{Key: "after", Value: values}}, false, false)
+	if err != nil {
+		return
+	}
+	var doc map[string]json.RawMessage
+	if err = json.Unmarshal(b, &doc); err != nil {
+		return
+	}
+
+	ctx.Header(nextAfterHeader, string(doc["after"]))
+}
+
+// Returns true if the sort direction is descending
+func isDescending(direction interface{}) bool {
+	switch d := direction.(type) {
+	case int32:
+		return d < 0
+	case int64:
+		return d < 0
+	case int:
+		return d < 0
+	case float64:
+		return d < 0
+	}
+
+	return false
+}
+
+// Returns the value of the dotted path in the document and true, or false if it isn't found
+func lookupField(doc bson.D, path string) (interface{}, bool) {
+
+	key, rest, nested := strings.Cut(path, ".")
+	for _, e := range doc {
+		if e.Key != key {
+			continue
+		}
+		if !nested {
+			return e.Value, true
+		}
+		if sub, ok := e.Value.(bson.D); ok {
+			return lookupField(sub, rest)
+		}
+		return nil, false
+	}
+
+	return nil, false
+}
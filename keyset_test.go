@@ -0,0 +1,75 @@
+package gomongoapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Returns a test context of a request with the url params
+func newTestContext(target string) (*gin.Context, *httptest.ResponseRecorder) {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodPost, target, nil)
+	return ctx, rec
+}
+
+func TestKeysetPaging(t *testing.T) {
+
+	s := &server{}
+	id := primitive.NewObjectID()
+	sort := bson.D{{Key: "Time", Value: -1}}
+
+	// Sorts are kept as is and no next after value is set without the after param
+	ctx, rec := newTestContext("/")
+	plain, after, ok := s.getAfter(ctx, sort)
+	if !ok || after != nil || !reflect.DeepEqual(plain, sort) {
+		t.Fatalf("expected the sort to be kept without a keyset condition, got %v and %v", plain, after)
+	}
+	s.setNextAfter(ctx, plain, []bson.D{{{Key: "_id", Value: id}, {Key: "Time", Value: int32(5)}}})
+	if next := rec.Header().Get(nextAfterHeader); next != "" {
+		t.Fatalf("expected no next after header, got %s", next)
+	}
+
+	// The first page is sorted with the _id tiebreaker and its last document gives the next after value
+	ctx, rec = newTestContext("/?after=")
+	sort, after, ok = s.getAfter(ctx, sort)
+	if !ok || after != nil {
+		t.Fatalf("expected no keyset condition, got %v", after)
+	}
+	wantSort := bson.D{{Key: "Time", Value: -1}, {Key: "_id", Value: -1}}
+	if !reflect.DeepEqual(sort, wantSort) {
+		t.Fatalf("expected sort %v, got %v", wantSort, sort)
+	}
+
+	s.setNextAfter(ctx, sort, []bson.D{{{Key: "_id", Value: id}, {Key: "Time", Value: int32(5)}}})
+	next := rec.Header().Get(nextAfterHeader)
+	if next != `[5,{"$oid":"`+id.Hex()+`"}]` {
+		t.Fatalf("unexpected next after header %s", next)
+	}
+
+	// The next page continues after both values, documents with the same time and a later _id are kept
+	ctx, _ = newTestContext("/?after=" + next)
+	_, after, ok = s.getAfter(ctx, bson.D{{Key: "Time", Value: -1}})
+	if !ok {
+		t.Fatal("expected after to be valid")
+	}
+	want := bson.M{"$or": bson.A{
+		bson.M{"Time": bson.M{"$lt": int32(5)}},
+		bson.M{"Time": int32(5), "_id": bson.M{"$lt": id}},
+	}}
+	if !reflect.DeepEqual(after, want) {
+		t.Errorf("expected condition %v, got %v", want, after)
+	}
+
+	// After must have a value per sort field
+	ctx, rec = newTestContext("/?after=5")
+	if _, _, ok = s.getAfter(ctx, bson.D{{Key: "Time", Value: -1}}); ok || rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a single value after to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,150 @@
+package gomongoapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Returns the most recent document of each group on the collection. /collections/:name/latestPerGroup
+// Valid URL parameters are 'database', 'readPreference', 'groupField', 'timeField', 'limit', 'allowDiskUse', 'format',
+// 'columns' and 'download'. 'groupField' is required, 'timeField' defaults to the time field of a time series collection.
+// Groups are sorted by their value and limited like find results.
+// Request body should have the filter used to match documents
+//	ex) Request Body: {"Type": "sensor"}
+func (s *server) collectionLatestPerGroup(ctx *gin.Context) {
+
+	// Get database name, return error if one isn't available
+	dbName, ok := s.getDatabaseName(ctx)
+	if !ok {
+		return
+	}
+
+	// Get collection name, return error if one isn't passed or allowed
+	collName, ok := s.getCollectionName(ctx)
+	if !ok {
+		return
+	}
+
+	// Get field to group by, return error if one isn't passed
+	groupField := strings.TrimPrefix(ctx.Query("groupField"), "$")
+	if groupField == "" {
+		s.sendError(ctx, http.StatusBadRequest, "GroupField was not passed, one is needed")
+		return
+	}
+
+	// Get time field, fall back to the time field of a time series collection
+	timeField := strings.TrimPrefix(ctx.Query("timeField"), "$")
+	if timeField == "" {
+		timeField = s.collectionTimeField(ctx.Request.Context(), dbName, collName)
+	}
+	if timeField == "" {
+		s.sendError(ctx, http.StatusBadRequest, "TimeField was not passed, one is needed")
+		return
+	}
+
+	// Get limit, if none was passed default to default value
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", s.findLimit))
+	if err != nil {
+		s.sendError(ctx, http.StatusBadRequest, "Limit is not an int: %s", err.Error())
+		return
+	}
+	if s.maxLimit != 0 && limit > s.maxLimit {
+		s.sendError(ctx, http.StatusBadRequest, "Passed limit is greater than max limit set by server")
+		return
+	}
+
+	// Get filter from request body
+	var filter bson.M
+	err = ctx.ShouldBindJSON(&filter)
+	if err != nil {
+		s.sendError(ctx, http.StatusBadRequest, "Error reading body request: %s", err.Error())
+		return
+	}
+	s.setAuditQuery(ctx, filter)
+	if filter == nil {
+		filter = bson.M{}
+	}
+	if !s.checkFilter(ctx, filter) {
+		return
+	}
+	if !s.checkRequireFilter(ctx, collName, filter) {
+		return
+	}
+
+	// Scope the filter to the collection base filter and the tenant of the request
+	filter, ok = s.scopeFilter(ctx, collName, filter)
+	if !ok {
+		return
+	}
+
+	// Let the filter interceptor rewrite or reject the filter
+	filter, ok = s.interceptFilter(ctx, dbName, collName, filter)
+	if !ok {
+		return
+	}
+
+	pipeLine := []bson.M{
+		{"$match": filter},
+		{"$sort": bson.D{{Key: timeField, Value: -1}}},
+		{"$group": bson.M{"_id": "$" + groupField, "latest": bson.M{"$first": "$$ROOT"}}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+	if limit > 0 {
+		pipeLine = append(pipeLine, bson.M{"$limit": limit})
+	}
+	pipeLine = append(pipeLine, bson.M{"$replaceRoot": bson.M{"newRoot": "$latest"}})
+
+	// Get allow disk use, bounded by the server default
+	allowDiskUse, ok := s.getAllowDiskUse(ctx)
+	if !ok {
+		return
+	}
+
+	opts := options.Aggregate()
+	opts.SetAllowDiskUse(allowDiskUse)
+
+	s.setDebugQuery(ctx, bson.M{"pipeline": pipeLine, "allowDiskUse": allowDiskUse})
+
+	// Get collection handle with the request read preference
+	coll, ok := s.getCollection(ctx, dbName, collName)
+	if !ok {
+		return
+	}
+
+	var cursor *mongo.Cursor
+	err = s.withRetry(ctx.Request.Context(), func() error {
+		var err error
+		cursor, err = coll.Aggregate(ctx.Request.Context(), pipeLine, opts)
+		return err
+	})
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error running latest per group: %s", err.Error())
+		return
+	}
+
+	// Decode results, a timeout may return partial results
+	res, partial, err := s.readCursor(ctx.Request.Context(), cursor)
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error decoding results: %s", err.Error())
+		return
+	}
+	s.encodeResults(res)
+	s.transformResults(ctx, res)
+
+	code := http.StatusOK
+	if len(res) == 0 && !partial {
+		code = s.emptyResultStatus
+	}
+	if partial {
+		ctx.Header(partialResultsHeader, "true")
+		code = http.StatusPartialContent
+	}
+
+	s.sendDocuments(ctx, code, res)
+}
@@ -0,0 +1,22 @@
+package gomongoapi
+
+import (
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Returns middleware that logs every api request.
+// The route is logged as its template, ex) /api/collections/:name/find, so it is safe to use as a label,
+// the collection name is logged as its own field.
+func (s *server) requestLogger() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		log.Printf("route=%s method=%s status=%d duration=%s collection=%s\n",
+			ctx.FullPath(), ctx.Request.Method, ctx.Writer.Status(), time.Since(start), s.collectionParam(ctx))
+	}
+}
@@ -0,0 +1,49 @@
+package gomongoapi
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache key of the database name listing, collection name listings are keyed by their database name
+const databasesCacheKey = ""
+
+// Cached database and collection name listings, entries are used until their ttl passes
+type metadataCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]metadataEntry
+}
+
+// Cached name listing and the time it expires
+type metadataEntry struct {
+	names   []string
+	expires time.Time
+}
+
+// Returns the names listed by list, or the cached names of the key if they haven't expired.
+// Without a ttl the names are always listed. Errors are not cached.
+func (mc *metadataCache) get(key string, list func() ([]string, error)) ([]string, error) {
+
+	if mc.ttl <= 0 {
+		return list()
+	}
+
+	mc.mu.Lock()
+	entry, ok := mc.entries[key]
+	mc.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.names, nil
+	}
+
+	names, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	mc.mu.Lock()
+	mc.entries[key] = metadataEntry{names: names, expires: time.Now().Add(mc.ttl)}
+	mc.mu.Unlock()
+
+	return names, nil
+}
@@ -2,13 +2,29 @@ package gomongoapi
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/tag"
 )
 
 var (
 	ErrInvalidCustomRouteName = errors.New("invalid custom route name")
+	ErrInvalidNamespace       = errors.New("invalid database namespace")
+	ErrMaxCustomRoutes        = errors.New("max number of custom routes registered")
+	ErrRouteConflict          = errors.New("route conflicts with a built-in route")
+	ErrInvalidFormat          = errors.New("invalid output format")
+	ErrInvalidReadConcern     = errors.New("invalid read concern level")
+	ErrInvalidVariableRoute   = errors.New("invalid variable route")
 )
 
 // Options contains options to configure the mongo api server
@@ -19,9 +35,15 @@ type Options struct {
 	// Server address that the gin router with use. Default is :8080
 	Address string
 
-	// Optional field to set custom route group name which will be used if user adds custom routes. Default is 'custom'.
+	// Optional field to set custom route group name which will be used if user adds custom routes. Default is '/custom'.
 	CustomRouteName string
 
+	// Max number of custom routes that can be registered. Default is 0 which means no limit.
+	MaxCustomRoutes int
+
+	// Logs every api request with its route template and collection name. Default is false.
+	LogRequests bool
+
 	// Mongo Client options. Default is an empty set of options.
 	MongoClientOpts *options.ClientOptions
 
@@ -31,19 +53,373 @@ type Options struct {
 	// An upper limit of the number of records that find can return. Default is 0 which means no limit.
 	FindMaxLimit int
 
+	// Fields excluded from find results by default, keyed by collection name.
+	// A client can still get them by naming them in its projection.
+	DefaultExcludeFields map[string][]string
+
+	// Checks that common query operators in filters have sensibly typed arguments before running them.
+	// Invalid filters return 400 describing the offending path. Default is false.
+	ValidateOperators bool
+
 	// Optional field if user wants to set a default database to use. If none is set then all databases will be queryable.
 	DefaultDB string
+
+	// Optional field to set retryable reads on the mongo client options. Default is nil which keeps the driver default.
+	RetryableReads *bool
+
+	// Number of times handlers will re-issue an operation that failed with a retryable error, network errors and errors of
+	// topology changes like NotWritablePrimary or ShutdownInProgress. Retries stack on top of the driver retryable reads, so
+	// with retryable reads on each attempt is already retried once by the driver. Default is 0 which means no retries.
+	MaxRetries int
+
+	// Wraps responses as {"status": "ok", "data": ...} and errors as {"status": "error", "message": ...}.
+	// Default is false which returns raw responses, as expected by Grafana.
+	ResponseEnvelope bool
+
+	// How BSON timestamps are represented in results. Default is TimestampRaw which keeps them as {"T": ..., "I": ...}.
+	TimestampFormat TimestampFormat
+
+	// How the _id field of find and aggregate results is handled. Default is IDKeep which leaves it as is.
+	IDHandling IDHandling
+
+	// Enables the write routes, insert, insertMany and upsert. Default is false which returns 403 on write routes.
+	WritesEnabled bool
+
+	// Enables destructive write routes, drop. Writes must also be enabled. Default is false which returns 403 on them.
+	DestructiveOpsEnabled bool
+
+	// Field holding the document time, used by the time based conveniences like the default find sort. Default is none.
+	TimeField string
+
+	// Sorts by the time field ascending, oldest first, when a request doesn't pass a sort.
+	// Default is false which sorts descending, newest first.
+	TimeFieldSortAscending bool
+
+	// Collections that can be queried, including through aggregate stages like $lookup and $unionWith.
+	// Default is nil which allows all collections.
+	AllowedCollections []string
+
+	// Max nesting depth of filters, deeper filters return 400. Default is 0 which means no limit.
+	MaxFilterDepth int
+
+	// Rejects filters using $regex or $where with 400. Default is false.
+	DisallowRegex bool
+
+	// Validators run on documents before they are written, keyed by collection name. Insert routes validate each
+	// document and upsert validates the update document. A returned error rejects the write with 422.
+	WriteValidators map[string]func(doc map[string]interface{}) error
+
+	// Keeps the fields of result documents in their stored order when marshaled to JSON.
+	// Default is false which marshals documents as maps, with fields sorted by name.
+	PreserveFieldOrder bool
+
+	// Max number of attempts to connect to MongoDB when the server starts. Default is 0 which makes a single attempt.
+	StartupRetryAttempts int
+
+	// Wait before retrying to connect to MongoDB when the server starts, it doubles after each attempt.
+	StartupRetryBackoff time.Duration
+
+	// Stops find and aggregate from using disk. When false requests can still disable it with the 'allowDiskUse' url param,
+	// when true they can't enable it. Default is false which allows disk use.
+	DisallowDiskUse bool
+
+	// How long a paged aggregate cursor is kept open without being read before it is closed, and how long a change stream
+	// is kept open without sending to its client. Default is 5 minutes.
+	CursorTTL time.Duration
+
+	// Proxies, as IPs or CIDRs, trusted to report the client IP through headers like X-Forwarded-For.
+	// It is applied to the gin router when the server starts and decides the IP returned by ctx.ClientIP(),
+	// which IP based middleware like rate limits and per IP quotas rely on. Without it behind a load balancer,
+	// the client IP may be the balancer's or spoofed by the client. Default is nil which keeps the router setting.
+	TrustedProxies []string
+
+	// An upper limit of the number of records that aggregate can return, enforced with a trailing $limit stage.
+	// It is independent of FindMaxLimit. Default is 0 which means no limit.
+	AggregateMaxLimit int
+
+	// Returns the documents read so far when a find or aggregate cursor is cut short by a timeout,
+	// with status 206 and the X-Partial-Results: true header. Default is false which returns an error.
+	ReturnPartialOnTimeout bool
+
+	// Returns the filter scoping a request to its tenant, ex) from an auth header. It is ANDed into every find, count
+	// and aggregate, as a leading $match, so clients can't see other tenants' data. If it returns nil the request gets 403.
+	// Aggregate stages reading other collections, like $lookup, are rejected when it is set. Inserted and upserted
+	// documents are stamped with its fields and upsert filters are scoped by it, writes get 403 if it has operators
+	// or the update changes its fields. Drops are rejected when it is set. Default is nil.
+	TenantFilter func(ctx *gin.Context) bson.M
+
+	// Status code sent when a find or aggregate returns no documents. 204 sends no body, any other code sends
+	// an empty array. Default is 200.
+	EmptyResultStatus int
+
+	// Url params other than the route params are added to find and count filters as equality matches,
+	// ex) ?UserName=Jon. Default is false.
+	QueryParamFilters bool
+
+	// Types query param filter values are converted to, keyed by collection name then field name.
+	// Valid types are int, float, bool and date. Fields without a type are matched as strings.
+	ParamTypeHints map[string]map[string]string
+
+	// Exposes the GET /api/routes route listing every route of the server. Default is false.
+	ExposeRoutes bool
+
+	// Where collection routes read the collection name from. With CollectionSourceQuery the routes have no
+	// collection name segment and it is passed in the 'collection' url param, ex) /api/collections/find?collection=users.
+	// Default is CollectionSourcePath.
+	CollectionSource CollectionSource
+
+	// Max number of documents sent by any read route, regardless of the query limit or the route that produced them.
+	// Extra documents are dropped and the X-Results-Truncated: true header is set. Default is 0 which means no cap.
+	HardResultCap int
+
+	// Max time to write a response, from the end of reading the request headers, set as the http server WriteTimeout.
+	// It bounds slow result encoding and writes independently of the query timeout. It also applies to streamed responses,
+	// like ndjson and csv downloads, which are cut off once it passes, so set it above the longest expected download.
+	// Default is 0 which means no timeout.
+	ResponseWriteTimeout time.Duration
+
+	// Max number of batch sub queries run at the same time. Default is 4.
+	BatchConcurrency int
+
+	// Max number of sub queries in a batch request, larger batches return 400. Default is 0 which means no limit.
+	MaxBatchSize int
+
+	// Returns 416 with the total count when a find requests the total with 'withTotal=true' and its skip is past
+	// the matching documents, so clients can tell a page past the end from an empty result. Default is false.
+	SkipRangeCheck bool
+
+	// How aggregate pipelines with a $group or $sort stage that has no $match before it are handled when disk use
+	// isn't allowed, as they can go over the memory limit. Default is GroupMemoryCheckOff.
+	GroupMemoryCheck GroupMemoryCheck
+
+	// How NaN and infinite floats in results are represented, JSON can't encode them so they would fail the whole
+	// response. Default is NonFiniteNull.
+	NonFiniteFloatHandling NonFiniteFloatHandling
+
+	// How BSON binary values in results are represented. Default is BinaryRaw.
+	BinaryHandling BinaryHandling
+
+	// Requests with 'debug=true' get the query the server ran, after the tenant filter and other server side changes,
+	// as extended JSON in the X-Debug-Query header. Default is false.
+	DebugQueries bool
+
+	// Returns the database a request may use, ex) from validated JWT claims. When set it takes precedence over
+	// database namespaces, the default db and the 'database' url param. If it returns an error the request gets 403. Default is nil.
+	DatabaseResolver func(ctx *gin.Context) (string, error)
+
+	// Number of documents sampled by discovery routes, like /collections/:name/fields. Default is 100.
+	DiscoverySampleSize int
+
+	// Max time a discovery route may take, so it can't hang a dashboard editor on a large collection. Default is 2s.
+	DiscoveryTimeout time.Duration
+
+	// Origins allowed to make cross origin requests to the /api group, "*" allows any origin. Default is nil which
+	// sends no CORS headers.
+	APICORSOrigins []string
+
+	// Origins allowed to make cross origin requests to the custom group, "*" allows any origin. Default is nil which
+	// sends no CORS headers.
+	CustomCORSOrigins []string
+
+	// Databases pinged when the server starts, so the first query to each doesn't pay the connection setup.
+	// Failures are logged without stopping the server. Default is nil.
+	WarmupDatabases []string
+
+	// Max number of values returned by the distinct and groupCount routes, extra values are dropped and the
+	// X-Truncated: true header is set. Default is 0 which means no limit.
+	MaxDistinctValues int
+
+	// Max nesting depth of the 'projection' url param, deeper projections return 400. The projection document itself
+	// is depth 1. Default is 10 with ServerOptions, 0 means no limit.
+	MaxProjectionDepth int
+
+	// Geometry field of the collection documents used by format=geojson, keyed by collection name.
+	GeoJSONFields map[string]string
+
+	// Read preferences of collections, keyed by collection name. They override the client read preference and the
+	// 'readPreference' url param. Default is nil.
+	CollectionReadPreferences map[string]*readpref.ReadPref
+
+	// Called with the filter of every route reading documents before it runs, after the tenant filter is applied. It returns
+	// the filter to run, so it can rewrite it, or an error which responds 403. Aggregates pass the filter of their leading
+	// $match stages, which is replaced by the returned one. Change streams can't be filtered, so watching a collection is
+	// rejected if a filter is returned. Default is nil.
+	FilterInterceptor func(ctx *gin.Context, db, coll string, filter bson.M) (bson.M, error)
+
+	// Hides the database names on /api/databases. When true it returns 403, unless a default db or database resolver
+	// is set, in which case only that database is listed as before. Default is false which lists all database names.
+	HideDatabaseList bool
+
+	// Expression language of the 'select' url param, which picks values out of find and aggregate results before they
+	// are sent, ex) select=$[*].Address.City. Default is SelectNone which disables the param.
+	SelectLanguage SelectLanguage
+
+	// Rejects finds, counts, group counts, distincts, time series and batch sub queries with an empty or missing filter
+	// with 400, to guard against accidental full collection scans. Default is false.
+	RequireFilter bool
+
+	// Collections where filtered routes need a filter, like RequireFilter but only for these collections.
+	RequireFilterCollections []string
+
+	// Indexes created on collections of the default db when the server starts, keyed by collection name.
+	// Failures are logged without stopping the server. Default is nil.
+	EnsuredIndexes map[string][]mongo.IndexModel
+
+	// Max length of the query string of api requests, longer ones return 414. Default is 0 which means no limit.
+	MaxQueryStringLength int
+
+	// Max number of query params of api requests, repeated params count once per value, more return 400.
+	// Default is 0 which means no limit.
+	MaxQueryParams int
+
+	// How the aggregate route handles an empty or missing pipeline, which would return every document of the
+	// collection. Default is EmptyPipelineAllow.
+	EmptyPipeline EmptyPipeline
+
+	// How often cursors and change streams idle for longer than the cursor ttl are looked for and closed. Default is 0
+	// which uses half the cursor ttl, the min is 1s.
+	CursorReapInterval time.Duration
+
+	// Detects time series collections from their options. Finds on them are sorted by their time field when no sort
+	// is passed, the timeseries route defaults to their time field, and upserts are rejected. Default is false.
+	TimeSeriesAware bool
+
+	// Filters ANDed into every query on the collection, keyed by collection name, ex) {"deleted": {"$ne": true}} for
+	// soft deletes. Clients can't see or override them. Aggregate stages reading a collection with a base filter are rejected.
+	BaseFilters map[string]bson.M
+
+	// Output format of find and aggregate results when neither the 'format' url param nor the Accept header picks one,
+	// one of json, ndjson, csv, geojson, table or infinity. Default is json.
+	DefaultFormat string
+
+	// Called with an audit entry of every api request once it is handled, including rejected requests.
+	// Default is nil which means no audit log.
+	AuditLogger func(entry AuditEntry)
+
+	// Gin context key the identity of the client is read from for audit entries, ex) set by an auth middleware.
+	// Default is empty which means entries have no identity.
+	AuditIdentityKey string
+
+	// Max number of top level fields of the 'projection' url param, projections with more return 400.
+	// Default is 0 which means no limit.
+	MaxProjectionFields int
+
+	// Time database and collection name listings are cached for, repeated listings within it don't query the cluster.
+	// Default is 0 which means listings aren't cached.
+	MetadataCacheTTL time.Duration
+
+	// Max size in bytes of the aggregate request body, larger pipelines return 413.
+	// Default is 0 which means no limit.
+	MaxPipelineBytes int64
+
+	// If true the count route response follows the output format of the request like find results, ex) a single cell
+	// table for format=table. The json format keeps the {"Count": n} object. Default is false which means count always
+	// returns {"Count": n}.
+	CountFollowsFormat bool
+
+	// If true finds whose winning plan is a collection scan are rejected with 400 before they run. Each find is explained
+	// first, which costs an extra round trip to the cluster. Default is false.
+	RejectCollectionScans bool
+
+	// Read concern level of aggregates, one of local, available, majority, linearizable or snapshot, ex) majority to
+	// avoid orphaned documents on a sharded cluster. The 'readConcern' url param overrides it.
+	// Default is empty which means the client read concern is used.
+	AggregateReadConcern string
+
+	// Max number of change streams open at once on the watch route, each one holds a connection of the pool.
+	// Requests over it return 503 with a Retry-After header. Default is 0 which means no limit.
+	MaxChangeStreams int
+
+	// If true the collections route lists system collections, ex) system.views. Default is false.
+	IncludeSystemCollections bool
+
+	// Transformers applied in order to each find, aggregate and latestPerGroup result document after it is encoded.
+	// Streamed CSV rows are transformed one at a time as they are read, and their columns are sampled after transforming.
+	// Results decoded into a registered result type are not transformed. Default is none.
+	ResultTransformers []ResultTransformer
+
+	// Max number of values of a $in or $nin array in filters, filters with larger arrays return 400.
+	// Default is 0 which means no limit.
+	MaxInArraySize int
+
+	// Interval mongo is pinged at in the background for the health route, which then returns the cached status.
+	// Default is 0 which means the health route pings mongo on every request.
+	HealthCheckInterval time.Duration
+
+	// Pairs of strings replaced in the keys of result documents and their nested documents, ex) dots with underscores,
+	// for consumers that can't handle keys with dots or dollar signs. The replacement is one way, replaced keys can't be
+	// used to query the original fields. Default is none.
+	KeyCharReplacements []string
+
+	// Max time a collection route operation may take, the request is canceled once it passes. Route timeouts override
+	// it per operation. Default is 0 which means no timeout.
+	OperationTimeout time.Duration
+
+	// Max time of a collection route operation keyed by the operation, which is the last segment of the route path,
+	// ex) find, count, aggregate. Overrides the operation timeout, 0 means no timeout for the operation. Default is none.
+	RouteTimeouts map[string]time.Duration
+
+	// Max number of documents a find may scan, finds scanning more return 400. Mongo can't cap scanned documents, so
+	// each find is first explained with execution stats, which runs its plan, bounded by the scan check timeout.
+	// Finds that can't be explained within the timeout are rejected. Default is 0 which means no limit.
+	MaxDocsScanned int64
+
+	// Max time the explain checking the documents a find scans may take. Default is 0 which means 1 second.
+	ScanCheckTimeout time.Duration
+
+	// Timezone dates in results are converted to, they are sent as strings with the timezone offset,
+	// ex) 2023-04-01T12:00:00.000+02:00. Default is nil which means dates are sent as is in UTC.
+	OutputLocation *time.Location
+
+	// If true the find, count and aggregate routes reject requests without a Content-Type: application/json header
+	// with 415. Default is false.
+	RequireJSONContentType bool
+
+	// Max 'batchSize' url param of the find and aggregate routes, the number of documents the driver fetches per round
+	// trip. Default is 0 which means no limit.
+	MaxCursorBatchSize int
+
+	// If true api requests with url params the routes don't read return 400 listing them, catching typos like ?limt=10.
+	// With query param filters only params with a type hint on the collection can be used as filters. Default is false.
+	StrictQueryParams bool
+
+	// Client options of a separate connection used by the admin routes, like stats, ex) for an admin cluster.
+	// Default is nil which means the admin routes use the main client.
+	AdminClientOpts *options.ClientOptions
+
+	// Enables the export route streaming every document of a collection. It reads whole collections, so it is off
+	// by default. Default is false.
+	ExportEnabled bool
 }
 
-// Returns server options with default values
+// Custom route group name used when none is set
+const defaultCustomRouteName = "/custom"
+
+// Returns server options with default values.
+// Options left at their zero value keep the behavior of the defaults, except the find limit and max projection depth which have no limit.
 func ServerOptions() *Options {
 	return &Options{
-		Router:          gin.Default(),
-		Address:         ":8080",
-		CustomRouteName: "custom",
-		MongoClientOpts: options.Client(),
-		FindLimit:       1000,
-		FindMaxLimit:    0,
+		Router:                 gin.Default(),
+		Address:                ":8080",
+		CustomRouteName:        defaultCustomRouteName,
+		MongoClientOpts:        options.Client(),
+		FindLimit:              1000,
+		FindMaxLimit:           0,
+		TimestampFormat:        TimestampRaw,
+		IDHandling:             IDKeep,
+		CursorTTL:              defaultCursorTTL,
+		EmptyResultStatus:      http.StatusOK,
+		CollectionSource:       CollectionSourcePath,
+		BatchConcurrency:       defaultBatchConcurrency,
+		GroupMemoryCheck:       GroupMemoryCheckOff,
+		NonFiniteFloatHandling: NonFiniteNull,
+		BinaryHandling:         BinaryRaw,
+		DiscoverySampleSize:    defaultDiscoverySampleSize,
+		DiscoveryTimeout:       defaultDiscoveryTimeout,
+		MaxProjectionDepth:     defaultMaxProjectionDepth,
+		SelectLanguage:         SelectNone,
+		EmptyPipeline:          EmptyPipelineAllow,
+		DefaultFormat:          formatJSON,
 	}
 }
 
@@ -57,17 +433,40 @@ func (o *Options) SetAddress(address string) {
 	o.Address = address
 }
 
-// SetCustomRouteName sets custom route name.
+// SetCustomRouteName sets custom route name, ex) /custom.
+// Returns ErrInvalidCustomRouteName wrapped with the reason if the name is not a clean path starting with a slash,
+// has whitespace, path params or wildcards, or is root or inside /api.
 func (o *Options) SetCustomRouteName(customRouteName string) error {
-	// Ensure custom route is not root or api
-	if customRouteName == `/` || customRouteName == `/api` {
-		return ErrInvalidCustomRouteName
+	if err := validateCustomRouteName(customRouteName); err != nil {
+		return err
 	}
 
 	o.CustomRouteName = customRouteName
 	return nil
 }
 
+// Returns an error describing why the custom route name is invalid, or nil if it is valid
+func validateCustomRouteName(name string) error {
+	switch {
+	case !strings.HasPrefix(name, "/"):
+		return fmt.Errorf("%w: %q must start with a slash", ErrInvalidCustomRouteName, name)
+	case strings.IndexFunc(name, unicode.IsSpace) != -1:
+		return fmt.Errorf("%w: %q must not contain whitespace", ErrInvalidCustomRouteName, name)
+	case strings.Contains(name, "//"):
+		return fmt.Errorf("%w: %q must not contain duplicate slashes", ErrInvalidCustomRouteName, name)
+	case strings.ContainsAny(name, ":*"):
+		return fmt.Errorf("%w: %q must not contain path params or wildcards", ErrInvalidCustomRouteName, name)
+	case path.Clean(name) != name:
+		return fmt.Errorf("%w: %q must be a clean path without trailing slashes or dot segments", ErrInvalidCustomRouteName, name)
+	case name == "/":
+		return fmt.Errorf("%w: %q must not be root", ErrInvalidCustomRouteName, name)
+	case name == "/api" || strings.HasPrefix(name, "/api/"):
+		return fmt.Errorf("%w: %q must not be inside /api", ErrInvalidCustomRouteName, name)
+	}
+
+	return nil
+}
+
 // SetAddress sets the server address.
 func (o *Options) SetMongoClientOpts(mongoClientOpts *options.ClientOptions) {
 	o.MongoClientOpts = mongoClientOpts
@@ -88,3 +487,508 @@ func (o *Options) SetFindLimit(findLimit int) {
 func (o *Options) SetFindMaxLimit(findMaxLimit int) {
 	o.FindMaxLimit = findMaxLimit
 }
+
+// SetRetryableReads sets if the mongo client should retry reads.
+func (o *Options) SetRetryableReads(retryableReads bool) {
+	o.RetryableReads = &retryableReads
+}
+
+// SetMaxRetries sets the number of times an operation is re-issued after a retryable error, on top of the driver retryable reads.
+func (o *Options) SetMaxRetries(maxRetries int) {
+	o.MaxRetries = maxRetries
+}
+
+// SetResponseEnvelope sets if responses should be wrapped in a status envelope.
+func (o *Options) SetResponseEnvelope(responseEnvelope bool) {
+	o.ResponseEnvelope = responseEnvelope
+}
+
+// SetWritesEnabled sets if the write routes are enabled.
+func (o *Options) SetWritesEnabled(writesEnabled bool) {
+	o.WritesEnabled = writesEnabled
+}
+
+// SetTimestampFormat sets how BSON timestamps are represented in results.
+func (o *Options) SetTimestampFormat(timestampFormat TimestampFormat) {
+	o.TimestampFormat = timestampFormat
+}
+
+// SetLogRequests sets if api requests should be logged.
+func (o *Options) SetLogRequests(logRequests bool) {
+	o.LogRequests = logRequests
+}
+
+// SetDestructiveOpsEnabled sets if destructive write routes are enabled, writes must also be enabled.
+func (o *Options) SetDestructiveOpsEnabled(destructiveOpsEnabled bool) {
+	o.DestructiveOpsEnabled = destructiveOpsEnabled
+}
+
+// SetIDHandling sets how the _id field of find and aggregate results is handled.
+func (o *Options) SetIDHandling(idHandling IDHandling) {
+	o.IDHandling = idHandling
+}
+
+// SetMaxCustomRoutes sets the max number of custom routes that can be registered.
+func (o *Options) SetMaxCustomRoutes(maxCustomRoutes int) {
+	o.MaxCustomRoutes = maxCustomRoutes
+}
+
+// SetDefaultExcludeFields sets the fields excluded from find results on the collection by default.
+func (o *Options) SetDefaultExcludeFields(collection string, fields []string) {
+	if o.DefaultExcludeFields == nil {
+		o.DefaultExcludeFields = make(map[string][]string)
+	}
+
+	o.DefaultExcludeFields[collection] = fields
+}
+
+// SetValidateOperators sets if filter operators are validated before running them.
+func (o *Options) SetValidateOperators(validateOperators bool) {
+	o.ValidateOperators = validateOperators
+}
+
+// SetTimeField sets the field holding the document time.
+func (o *Options) SetTimeField(timeField string) {
+	o.TimeField = timeField
+}
+
+// SetTimeFieldSortAscending sets if the time field default sort is ascending.
+func (o *Options) SetTimeFieldSortAscending(timeFieldSortAscending bool) {
+	o.TimeFieldSortAscending = timeFieldSortAscending
+}
+
+// SetAllowedCollections sets the collections that can be queried.
+func (o *Options) SetAllowedCollections(allowedCollections []string) {
+	o.AllowedCollections = allowedCollections
+}
+
+// SetMaxFilterDepth sets the max nesting depth of filters.
+func (o *Options) SetMaxFilterDepth(maxFilterDepth int) {
+	o.MaxFilterDepth = maxFilterDepth
+}
+
+// SetDisallowRegex sets if filters using $regex or $where are rejected.
+func (o *Options) SetDisallowRegex(disallowRegex bool) {
+	o.DisallowRegex = disallowRegex
+}
+
+// SetWriteValidator sets the validator run on documents before they are written to the collection.
+func (o *Options) SetWriteValidator(collection string, fn func(doc map[string]interface{}) error) {
+	if o.WriteValidators == nil {
+		o.WriteValidators = make(map[string]func(doc map[string]interface{}) error)
+	}
+
+	o.WriteValidators[collection] = fn
+}
+
+// SetPreserveFieldOrder sets if result documents keep their stored field order.
+func (o *Options) SetPreserveFieldOrder(preserveFieldOrder bool) {
+	o.PreserveFieldOrder = preserveFieldOrder
+}
+
+// SetStartupRetry sets the max number of attempts to connect to MongoDB when the server starts
+// and the wait before the first retry, the wait doubles after each attempt.
+func (o *Options) SetStartupRetry(maxAttempts int, backoff time.Duration) {
+	o.StartupRetryAttempts = maxAttempts
+	o.StartupRetryBackoff = backoff
+}
+
+// SetAllowDiskUse sets if find and aggregate may use disk.
+func (o *Options) SetAllowDiskUse(allowDiskUse bool) {
+	o.DisallowDiskUse = !allowDiskUse
+}
+
+// SetCursorTTL sets how long a paged aggregate cursor is kept open without being read.
+func (o *Options) SetCursorTTL(cursorTTL time.Duration) {
+	o.CursorTTL = cursorTTL
+}
+
+// SetTrustedProxies sets the proxies trusted to report the client IP.
+func (o *Options) SetTrustedProxies(trustedProxies []string) {
+	o.TrustedProxies = trustedProxies
+}
+
+// SetAggregateMaxLimit sets the upper limit for aggregate results.
+func (o *Options) SetAggregateMaxLimit(aggregateMaxLimit int) {
+	o.AggregateMaxLimit = aggregateMaxLimit
+}
+
+// SetReturnPartialOnTimeout sets if partial results are returned when a timeout cuts a cursor short.
+func (o *Options) SetReturnPartialOnTimeout(returnPartialOnTimeout bool) {
+	o.ReturnPartialOnTimeout = returnPartialOnTimeout
+}
+
+// SetTenantFilter sets the function returning the filter that scopes a request to its tenant.
+func (o *Options) SetTenantFilter(tenantFilter func(ctx *gin.Context) bson.M) {
+	o.TenantFilter = tenantFilter
+}
+
+// SetEmptyResultStatus sets the status code sent when a find or aggregate returns no documents.
+func (o *Options) SetEmptyResultStatus(emptyResultStatus int) {
+	o.EmptyResultStatus = emptyResultStatus
+}
+
+// SetQueryParamFilters sets if url params are added to find and count filters.
+func (o *Options) SetQueryParamFilters(queryParamFilters bool) {
+	o.QueryParamFilters = queryParamFilters
+}
+
+// SetParamTypeHints sets the types query param filter values on the collection are converted to, keyed by field name.
+func (o *Options) SetParamTypeHints(collection string, types map[string]string) {
+	if o.ParamTypeHints == nil {
+		o.ParamTypeHints = make(map[string]map[string]string)
+	}
+
+	o.ParamTypeHints[collection] = types
+}
+
+// SetExposeRoutes sets if the route listing every route of the server is exposed.
+func (o *Options) SetExposeRoutes(exposeRoutes bool) {
+	o.ExposeRoutes = exposeRoutes
+}
+
+// SetCollectionSource sets where collection routes read the collection name from.
+func (o *Options) SetCollectionSource(collectionSource CollectionSource) {
+	o.CollectionSource = collectionSource
+}
+
+// SetHardResultCap sets the max number of documents sent by any read route.
+func (o *Options) SetHardResultCap(hardResultCap int) {
+	o.HardResultCap = hardResultCap
+}
+
+// SetResponseWriteTimeout sets the max time to write a response.
+func (o *Options) SetResponseWriteTimeout(responseWriteTimeout time.Duration) {
+	o.ResponseWriteTimeout = responseWriteTimeout
+}
+
+// SetBatchConcurrency sets the max number of batch sub queries run at the same time.
+func (o *Options) SetBatchConcurrency(batchConcurrency int) {
+	o.BatchConcurrency = batchConcurrency
+}
+
+// SetMaxBatchSize sets the max number of sub queries in a batch request.
+func (o *Options) SetMaxBatchSize(maxBatchSize int) {
+	o.MaxBatchSize = maxBatchSize
+}
+
+// SetSkipRangeCheck sets if a find skip past the matching documents returns 416.
+func (o *Options) SetSkipRangeCheck(skipRangeCheck bool) {
+	o.SkipRangeCheck = skipRangeCheck
+}
+
+// SetGroupMemoryCheck sets how aggregate pipelines that may go over the memory limit are handled.
+func (o *Options) SetGroupMemoryCheck(groupMemoryCheck GroupMemoryCheck) {
+	o.GroupMemoryCheck = groupMemoryCheck
+}
+
+// SetNonFiniteFloatHandling sets how NaN and infinite floats in results are represented.
+func (o *Options) SetNonFiniteFloatHandling(nonFiniteFloatHandling NonFiniteFloatHandling) {
+	o.NonFiniteFloatHandling = nonFiniteFloatHandling
+}
+
+// SetBinaryHandling sets how BSON binary values in results are represented.
+func (o *Options) SetBinaryHandling(binaryHandling BinaryHandling) {
+	o.BinaryHandling = binaryHandling
+}
+
+// SetDebugQueries sets if requests can get the query the server ran with 'debug=true'.
+func (o *Options) SetDebugQueries(debugQueries bool) {
+	o.DebugQueries = debugQueries
+}
+
+// SetDatabaseResolver sets the function returning the database a request may use.
+func (o *Options) SetDatabaseResolver(databaseResolver func(ctx *gin.Context) (string, error)) {
+	o.DatabaseResolver = databaseResolver
+}
+
+// SetDiscoverySampleSize sets the number of documents sampled by discovery routes.
+func (o *Options) SetDiscoverySampleSize(discoverySampleSize int) {
+	o.DiscoverySampleSize = discoverySampleSize
+}
+
+// SetDiscoveryTimeout sets the max time a discovery route may take.
+func (o *Options) SetDiscoveryTimeout(discoveryTimeout time.Duration) {
+	o.DiscoveryTimeout = discoveryTimeout
+}
+
+// SetAPICORS sets the origins allowed to make cross origin requests to the /api group.
+func (o *Options) SetAPICORS(origins []string) {
+	o.APICORSOrigins = origins
+}
+
+// SetCustomCORS sets the origins allowed to make cross origin requests to the custom group.
+func (o *Options) SetCustomCORS(origins []string) {
+	o.CustomCORSOrigins = origins
+}
+
+// SetWarmupDatabases sets the databases pinged when the server starts.
+func (o *Options) SetWarmupDatabases(warmupDatabases []string) {
+	o.WarmupDatabases = warmupDatabases
+}
+
+// SetMaxDistinctValues sets the max number of values returned by the distinct and groupCount routes.
+func (o *Options) SetMaxDistinctValues(maxDistinctValues int) {
+	o.MaxDistinctValues = maxDistinctValues
+}
+
+// SetMaxProjectionDepth sets the max nesting depth of projections.
+func (o *Options) SetMaxProjectionDepth(maxProjectionDepth int) {
+	o.MaxProjectionDepth = maxProjectionDepth
+}
+
+// SetGeoJSONField sets the geometry field of the collection documents used by format=geojson.
+func (o *Options) SetGeoJSONField(collection string, field string) {
+	if o.GeoJSONFields == nil {
+		o.GeoJSONFields = make(map[string]string)
+	}
+
+	o.GeoJSONFields[collection] = field
+}
+
+// SetCollectionReadPreference sets the read preference of the collection from its mode, ex) secondary, and optional tag sets.
+// Returns an error if the mode is invalid or can't have tag sets.
+func (o *Options) SetCollectionReadPreference(collection string, mode string, tagSets ...map[string]string) error {
+	readMode, err := readpref.ModeFromString(mode)
+	if err != nil {
+		return err
+	}
+
+	sets := make([]tag.Set, len(tagSets))
+	for i, tags := range tagSets {
+		sets[i] = tag.NewTagSetFromMap(tags)
+	}
+
+	var prefOpts []readpref.Option
+	if len(sets) > 0 {
+		prefOpts = append(prefOpts, readpref.WithTagSets(sets...))
+	}
+
+	readPref, err := readpref.New(readMode, prefOpts...)
+	if err != nil {
+		return err
+	}
+
+	if o.CollectionReadPreferences == nil {
+		o.CollectionReadPreferences = make(map[string]*readpref.ReadPref)
+	}
+
+	o.CollectionReadPreferences[collection] = readPref
+	return nil
+}
+
+// SetFilterInterceptor sets the function called with the filters of routes reading documents before they run.
+func (o *Options) SetFilterInterceptor(filterInterceptor func(ctx *gin.Context, db, coll string, filter bson.M) (bson.M, error)) {
+	o.FilterInterceptor = filterInterceptor
+}
+
+// SetExposeDatabaseList sets if /api/databases lists all database names.
+func (o *Options) SetExposeDatabaseList(exposeDatabaseList bool) {
+	o.HideDatabaseList = !exposeDatabaseList
+}
+
+// SetSelectLanguage sets the expression language of the 'select' url param.
+func (o *Options) SetSelectLanguage(selectLanguage SelectLanguage) {
+	o.SelectLanguage = selectLanguage
+}
+
+// SetRequireFilter sets if filtered routes on all collections need a filter.
+func (o *Options) SetRequireFilter(requireFilter bool) {
+	o.RequireFilter = requireFilter
+}
+
+// SetRequireFilterCollections sets the collections where filtered routes need a filter.
+func (o *Options) SetRequireFilterCollections(requireFilterCollections []string) {
+	o.RequireFilterCollections = requireFilterCollections
+}
+
+// SetEnsureIndexes sets the indexes created on the collection of the default db when the server starts.
+func (o *Options) SetEnsureIndexes(collection string, models []mongo.IndexModel) {
+	if o.EnsuredIndexes == nil {
+		o.EnsuredIndexes = make(map[string][]mongo.IndexModel)
+	}
+
+	o.EnsuredIndexes[collection] = models
+}
+
+// SetMaxQueryStringLength sets the max length of the query string of api requests.
+func (o *Options) SetMaxQueryStringLength(maxQueryStringLength int) {
+	o.MaxQueryStringLength = maxQueryStringLength
+}
+
+// SetMaxQueryParams sets the max number of query params of api requests.
+func (o *Options) SetMaxQueryParams(maxQueryParams int) {
+	o.MaxQueryParams = maxQueryParams
+}
+
+// SetEmptyPipeline sets how the aggregate route handles an empty or missing pipeline.
+func (o *Options) SetEmptyPipeline(emptyPipeline EmptyPipeline) {
+	o.EmptyPipeline = emptyPipeline
+}
+
+// SetCursorIdleTimeout sets how long a server side cursor or change stream is kept open without being read, and how often
+// idle ones are looked for and closed. Every read of a cursor and every send of a change stream resets its idle time.
+func (o *Options) SetCursorIdleTimeout(idleTimeout, reapInterval time.Duration) {
+	o.CursorTTL = idleTimeout
+	o.CursorReapInterval = reapInterval
+}
+
+// SetTimeSeriesAware sets if time series collections are detected and their defaults adjusted.
+func (o *Options) SetTimeSeriesAware(timeSeriesAware bool) {
+	o.TimeSeriesAware = timeSeriesAware
+}
+
+// SetBaseFilter sets the filter ANDed into every query on the collection.
+func (o *Options) SetBaseFilter(collection string, filter bson.M) {
+	if o.BaseFilters == nil {
+		o.BaseFilters = make(map[string]bson.M)
+	}
+
+	o.BaseFilters[collection] = filter
+}
+
+// SetDefaultFormat sets the output format of find and aggregate results when the request doesn't pick one.
+// Returns an error if the format is not one of json, ndjson, csv, geojson, table or infinity.
+func (o *Options) SetDefaultFormat(format string) error {
+	if !validFormat(format) {
+		return ErrInvalidFormat
+	}
+
+	o.DefaultFormat = format
+	return nil
+}
+
+// SetAuditLogger sets the function called with an audit entry of every api request once it is handled.
+func (o *Options) SetAuditLogger(logger func(entry AuditEntry)) {
+	o.AuditLogger = logger
+}
+
+// SetAuditIdentityKey sets the gin context key the identity of the client is read from for audit entries.
+func (o *Options) SetAuditIdentityKey(key string) {
+	o.AuditIdentityKey = key
+}
+
+// SetMaxProjectionFields sets the max number of fields a client projection can have.
+func (o *Options) SetMaxProjectionFields(maxProjectionFields int) {
+	o.MaxProjectionFields = maxProjectionFields
+}
+
+// SetMetadataCacheTTL sets the time database and collection name listings are cached for.
+func (o *Options) SetMetadataCacheTTL(ttl time.Duration) {
+	o.MetadataCacheTTL = ttl
+}
+
+// SetMaxPipelineBytes sets the max size in bytes of the aggregate request body.
+func (o *Options) SetMaxPipelineBytes(maxPipelineBytes int64) {
+	o.MaxPipelineBytes = maxPipelineBytes
+}
+
+// SetCountFollowsFormat sets if the count route response follows the output format of the request.
+func (o *Options) SetCountFollowsFormat(countFollowsFormat bool) {
+	o.CountFollowsFormat = countFollowsFormat
+}
+
+// SetRejectCollectionScans sets if finds that would scan the whole collection are rejected.
+func (o *Options) SetRejectCollectionScans(rejectCollectionScans bool) {
+	o.RejectCollectionScans = rejectCollectionScans
+}
+
+// SetAggregateReadConcern sets the default read concern level of aggregates.
+// Returns an error if the level is not one of local, available, majority, linearizable or snapshot.
+func (o *Options) SetAggregateReadConcern(level string) error {
+	if _, ok := readConcernLevel(level); !ok {
+		return ErrInvalidReadConcern
+	}
+
+	o.AggregateReadConcern = level
+	return nil
+}
+
+// SetMaxChangeStreams sets the max number of change streams open at once on the watch route.
+func (o *Options) SetMaxChangeStreams(maxChangeStreams int) {
+	o.MaxChangeStreams = maxChangeStreams
+}
+
+// SetIncludeSystemCollections sets if the collections route lists system collections.
+func (o *Options) SetIncludeSystemCollections(includeSystemCollections bool) {
+	o.IncludeSystemCollections = includeSystemCollections
+}
+
+// AddResultTransformer adds a transformer applied to each result document after the ones already added.
+func (o *Options) AddResultTransformer(transformer ResultTransformer) {
+	o.ResultTransformers = append(o.ResultTransformers, transformer)
+}
+
+// SetMaxInArraySize sets the max number of values of a $in or $nin array in filters.
+func (o *Options) SetMaxInArraySize(maxInArraySize int) {
+	o.MaxInArraySize = maxInArraySize
+}
+
+// SetHealthCheckInterval sets the interval mongo is pinged at in the background for the health route.
+func (o *Options) SetHealthCheckInterval(interval time.Duration) {
+	o.HealthCheckInterval = interval
+}
+
+// SetKeyCharReplacement adds a replacement of from with to in the keys of result documents, ex) SetKeyCharReplacement(".", "_").
+// All replacements are applied in a single pass, earlier added ones win when several match at the same position.
+func (o *Options) SetKeyCharReplacement(from, to string) {
+	o.KeyCharReplacements = append(o.KeyCharReplacements, from, to)
+}
+
+// SetOperationTimeout sets the max time a collection route operation may take.
+func (o *Options) SetOperationTimeout(timeout time.Duration) {
+	o.OperationTimeout = timeout
+}
+
+// SetRouteTimeout sets the max time of a collection route operation, ex) SetRouteTimeout("aggregate", time.Minute).
+func (o *Options) SetRouteTimeout(operation string, timeout time.Duration) {
+	if o.RouteTimeouts == nil {
+		o.RouteTimeouts = make(map[string]time.Duration)
+	}
+
+	o.RouteTimeouts[operation] = timeout
+}
+
+// SetMaxDocsScanned sets the max number of documents a find may scan and the max time checking it may take.
+// A check timeout of 0 uses the default of 1 second.
+func (o *Options) SetMaxDocsScanned(maxDocsScanned int64, checkTimeout time.Duration) {
+	o.MaxDocsScanned = maxDocsScanned
+	o.ScanCheckTimeout = checkTimeout
+}
+
+// SetOutputTimezone sets the timezone dates in results are converted to from its IANA name, ex) Europe/Paris.
+// Returns an error if the location can't be loaded.
+func (o *Options) SetOutputTimezone(loc string) error {
+	location, err := time.LoadLocation(loc)
+	if err != nil {
+		return err
+	}
+
+	o.OutputLocation = location
+	return nil
+}
+
+// SetRequireJSONContentType sets if the find, count and aggregate routes require a JSON request body.
+func (o *Options) SetRequireJSONContentType(requireJSONContentType bool) {
+	o.RequireJSONContentType = requireJSONContentType
+}
+
+// SetMaxCursorBatchSize sets the max 'batchSize' url param of the find and aggregate routes.
+func (o *Options) SetMaxCursorBatchSize(maxCursorBatchSize int) {
+	o.MaxCursorBatchSize = maxCursorBatchSize
+}
+
+// SetStrictQueryParams sets if api requests with unknown url params are rejected.
+func (o *Options) SetStrictQueryParams(strictQueryParams bool) {
+	o.StrictQueryParams = strictQueryParams
+}
+
+// SetAdminClient sets the client options of the connection used by the admin routes.
+func (o *Options) SetAdminClient(adminClientOpts *options.ClientOptions) {
+	o.AdminClientOpts = adminClientOpts
+}
+
+// SetExportEnabled sets if the export route is enabled.
+func (o *Options) SetExportEnabled(exportEnabled bool) {
+	o.ExportEnabled = exportEnabled
+}
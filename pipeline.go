@@ -0,0 +1,204 @@
+package gomongoapi
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GroupMemoryCheck is how aggregate pipelines that may go over the memory limit are handled
+type GroupMemoryCheck string
+
+const (
+	// GroupMemoryCheckOff runs pipelines without checking them
+	GroupMemoryCheckOff GroupMemoryCheck = "off"
+
+	// GroupMemoryCheckWarn logs pipelines with a $group or $sort stage that has no $match before it
+	GroupMemoryCheckWarn GroupMemoryCheck = "warn"
+
+	// GroupMemoryCheckReject returns 400 for pipelines with a $group or $sort stage that has no $match before it
+	GroupMemoryCheckReject GroupMemoryCheck = "reject"
+)
+
+// EmptyPipeline is how the aggregate route handles an empty or missing pipeline
+type EmptyPipeline string
+
+const (
+	// EmptyPipelineAllow runs the empty pipeline, returning every document of the collection
+	EmptyPipelineAllow EmptyPipeline = "allow"
+
+	// EmptyPipelineReject returns 400
+	EmptyPipelineReject EmptyPipeline = "reject"
+
+	// EmptyPipelineFindLimit runs the empty pipeline with a $limit of the find limit
+	EmptyPipelineFindLimit EmptyPipeline = "findLimit"
+)
+
+// Returns the names of the collections read by stages of the pipeline.
+// Stages checked are $lookup, $graphLookup and $unionWith, including the pipelines nested in them and in $facet.
+func referencedCollections(pipeLine []interface{}) []string {
+
+	var collNames []string
+	for _, stage := range pipeLine {
+		stageDoc, ok := stage.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for op, arg := range stageDoc {
+			switch op {
+			case "$lookup", "$graphLookup":
+				argDoc, _ := arg.(map[string]interface{})
+				if from, ok := argDoc["from"].(string); ok {
+					collNames = append(collNames, from)
+				}
+				if nested, ok := argDoc["pipeline"].([]interface{}); ok {
+					collNames = append(collNames, referencedCollections(nested)...)
+				}
+			case "$unionWith":
+				switch v := arg.(type) {
+				case string:
+					collNames = append(collNames, v)
+				case map[string]interface{}:
+					if coll, ok := v["coll"].(string); ok {
+						collNames = append(collNames, coll)
+					}
+					if nested, ok := v["pipeline"].([]interface{}); ok {
+						collNames = append(collNames, referencedCollections(nested)...)
+					}
+				}
+			case "$facet":
+				facets, _ := arg.(map[string]interface{})
+				for _, facet := range facets {
+					if nested, ok := facet.([]interface{}); ok {
+						collNames = append(collNames, referencedCollections(nested)...)
+					}
+				}
+			}
+		}
+	}
+
+	return collNames
+}
+
+// Returns true if the first stage of the pipeline is a $match on the field
+func matchesField(pipeLine []interface{}, field string) bool {
+
+	if len(pipeLine) == 0 {
+		return false
+	}
+
+	var stageDoc map[string]interface{}
+	switch v := pipeLine[0].(type) {
+	case map[string]interface{}:
+		stageDoc = v
+	case bson.M:
+		stageDoc = v
+	}
+
+	var match map[string]interface{}
+	switch v := stageDoc["$match"].(type) {
+	case map[string]interface{}:
+		match = v
+	case bson.M:
+		match = v
+	}
+
+	_, ok := match[field]
+	return ok
+}
+
+// Returns the first $group or $sort stage of the pipeline that has no $match stage before it, empty if there is none.
+// Without a $match to limit cardinality these stages can go over the memory limit when disk use isn't allowed.
+func unboundedStage(pipeLine []interface{}) string {
+
+	for _, stage := range pipeLine {
+		var stageDoc map[string]interface{}
+		switch v := stage.(type) {
+		case map[string]interface{}:
+			stageDoc = v
+		case bson.M:
+			stageDoc = v
+		}
+
+		if _, ok := stageDoc["$match"]; ok {
+			return ""
+		}
+		for _, op := range []string{"$group", "$sort"} {
+			if _, ok := stageDoc[op]; ok {
+				return op
+			}
+		}
+	}
+
+	return ""
+}
+
+// Returns true if the aggregate request body is within the max pipeline bytes.
+// The body is read up to the limit and put back so it can still be bound, larger bodies get a 413 response.
+func (s *server) checkPipelineSize(ctx *gin.Context) bool {
+
+	if s.maxPipelineBytes <= 0 || ctx.Request.Body == nil {
+		return true
+	}
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, s.maxPipelineBytes+1))
+	if err != nil {
+		s.sendError(ctx, http.StatusBadRequest, "Error reading body request: %s", err.Error())
+		return false
+	}
+	if int64(len(body)) > s.maxPipelineBytes {
+		s.sendError(ctx, http.StatusRequestEntityTooLarge, "Pipeline is larger than max pipeline bytes %d set by server", s.maxPipelineBytes)
+		return false
+	}
+
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return true
+}
+
+// Returns the filter of the $match stages the pipeline starts with and the stages after them.
+// Several leading $match stages are ANDed, the filter is empty if the pipeline doesn't start with a $match.
+func leadingMatch(pipeLine []interface{}) (bson.M, []interface{}) {
+
+	var filters bson.A
+	i := 0
+	for ; i < len(pipeLine); i++ {
+		var stageDoc map[string]interface{}
+		switch v := pipeLine[i].(type) {
+		case map[string]interface{}:
+			stageDoc = v
+		case bson.M:
+			stageDoc = v
+		}
+		if len(stageDoc) != 1 {
+			break
+		}
+
+		var match bson.M
+		switch v := stageDoc["$match"].(type) {
+		case map[string]interface{}:
+			match = v
+		case bson.M:
+			match = v
+		}
+		if match == nil {
+			break
+		}
+
+		if len(match) > 0 {
+			filters = append(filters, match)
+		}
+	}
+
+	switch len(filters) {
+	case 0:
+		return bson.M{}, pipeLine[i:]
+	case 1:
+		return filters[0].(bson.M), pipeLine[i:]
+	}
+
+	return bson.M{"$and": filters}, pipeLine[i:]
+}
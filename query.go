@@ -0,0 +1,367 @@
+package gomongoapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+)
+
+// Default max nesting depth of projections
+const defaultMaxProjectionDepth = 10
+
+// Returns the projection to use for a find on the collection and true.
+// The 'projection' url param is a JSON projection document, ex) {"Name": 1, "Email": 1}
+// The default excluded fields of the collection are added unless the projection is an inclusion projection,
+// which already leaves them out, or it names them itself.
+// If the projection is invalid an error response is sent and false is returned.
+func (s *server) getProjection(ctx *gin.Context, collName string) (bson.M, bool) {
+
+	projection := bson.M{}
+	if param, ok := ctx.GetQuery("projection"); ok {
+		err := json.Unmarshal([]byte(param), &projection)
+		if err != nil {
+			s.sendError(ctx, http.StatusBadRequest, "Projection is not a valid JSON document: %s", err.Error())
+			return nil, false
+		}
+
+		if s.maxProjectionDepth != 0 {
+			if depth := filterDepth(projection); depth > s.maxProjectionDepth {
+				s.sendError(ctx, http.StatusBadRequest, "Projection depth %d is greater than max depth %d set by server", depth, s.maxProjectionDepth)
+				return nil, false
+			}
+		}
+
+		if s.maxProjectionFields != 0 && len(projection) > s.maxProjectionFields {
+			s.sendError(ctx, http.StatusBadRequest, "Projection has %d fields, more than max fields %d set by server", len(projection), s.maxProjectionFields)
+			return nil, false
+		}
+	}
+
+	return s.excludeDefaultFields(collName, projection), true
+}
+
+// Returns the projection with the default excluded fields of the collection added to it.
+// Inclusion projections already leave them out and fields named by the projection are kept as is.
+func (s *server) excludeDefaultFields(collName string, projection bson.M) bson.M {
+
+	if !isInclusionProjection(projection) {
+		for _, field := range s.defaultExcludeFields[collName] {
+			if _, ok := projection[field]; !ok {
+				projection[field] = 0
+			}
+		}
+	}
+
+	return projection
+}
+
+// Returns true if the projection includes any field other than _id
+func isInclusionProjection(projection bson.M) bool {
+
+	for field, value := range projection {
+		if field == "_id" {
+			continue
+		}
+
+		switch v := value.(type) {
+		case float64:
+			if v != 0 {
+				return true
+			}
+		case bool:
+			if v {
+				return true
+			}
+		default:
+			// Anything else is a projection expression which includes the field
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the sort to use for a find and true.
+// The 'sort' url param is a JSON sort document, ex) {"Age": -1, "Name": 1}, the order of its fields is kept.
+// If no sort is passed and a time field is set, results are sorted by the time field in the server default direction.
+// If the sort is invalid an error response is sent and false is returned.
+func (s *server) getSort(ctx *gin.Context) (bson.D, bool) {
+
+	if param, ok := ctx.GetQuery("sort"); ok {
+		var sort bson.D
+		err := bson.UnmarshalExtJSON([]byte(param), false, &sort)
+		if err != nil {
+			s.sendError(ctx, http.StatusBadRequest, "Sort is not a valid JSON document: %s", err.Error())
+			return nil, false
+		}
+
+		return sort, true
+	}
+
+	if s.timeField != "" {
+		return bson.D{{Key: s.timeField, Value: s.timeSortDirection()}}, true
+	}
+
+	return nil, true
+}
+
+// Returns the server default sort direction of time fields
+func (s *server) timeSortDirection() int {
+	if s.timeFieldSortAscending {
+		return 1
+	}
+
+	return -1
+}
+
+// Returns if the operation may use disk and true.
+// The 'allowDiskUse' url param overrides the server default, but it can't enable disk use when the server doesn't allow it.
+// If the param is invalid an error response is sent and false is returned.
+func (s *server) getAllowDiskUse(ctx *gin.Context) (bool, bool) {
+
+	param, ok := ctx.GetQuery("allowDiskUse")
+	if !ok {
+		return s.allowDiskUse, true
+	}
+
+	allowDiskUse, err := strconv.ParseBool(param)
+	if err != nil {
+		s.sendError(ctx, http.StatusBadRequest, "AllowDiskUse is not a bool: %s", err.Error())
+		return false, false
+	}
+
+	if allowDiskUse && !s.allowDiskUse {
+		s.sendError(ctx, http.StatusBadRequest, "Disk use is not allowed by server")
+		return false, false
+	}
+
+	return allowDiskUse, true
+}
+
+// Returns the max execution time of the operation and true, 0 if the 'maxTimeMS' url param isn't passed.
+// If the param isn't a positive int an error response is sent and false is returned.
+func (s *server) getMaxTime(ctx *gin.Context) (time.Duration, bool) {
+
+	param, ok := ctx.GetQuery("maxTimeMS")
+	if !ok {
+		return 0, true
+	}
+
+	maxTimeMS, err := strconv.ParseInt(param, 10, 64)
+	if err != nil || maxTimeMS <= 0 {
+		s.sendError(ctx, http.StatusBadRequest, "MaxTimeMS is not a positive int")
+		return 0, false
+	}
+
+	return time.Duration(maxTimeMS) * time.Millisecond, true
+}
+
+// Returns the read concern of the level and true, or false if the level is unknown
+func readConcernLevel(level string) (*readconcern.ReadConcern, bool) {
+	switch level {
+	case "local":
+		return readconcern.Local(), true
+	case "available":
+		return readconcern.Available(), true
+	case "majority":
+		return readconcern.Majority(), true
+	case "linearizable":
+		return readconcern.Linearizable(), true
+	case "snapshot":
+		return readconcern.Snapshot(), true
+	}
+
+	return nil, false
+}
+
+// Returns the collection with the aggregate read concern applied and true.
+// The 'readConcern' url param overrides the server default, without either the collection is returned as is.
+// If the level is unknown an error response is sent and false is returned.
+func (s *server) withAggregateReadConcern(ctx *gin.Context, coll *mongo.Collection) (*mongo.Collection, bool) {
+
+	level := ctx.DefaultQuery("readConcern", s.aggregateReadConcern)
+	if level == "" {
+		return coll, true
+	}
+
+	readConcern, ok := readConcernLevel(level)
+	if !ok {
+		s.sendError(ctx, http.StatusBadRequest, "Read concern must be one of local, available, majority, linearizable or snapshot")
+		return nil, false
+	}
+
+	coll, err := coll.Clone(options.Collection().SetReadConcern(readConcern))
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error setting read concern: %s", err.Error())
+		return nil, false
+	}
+
+	return coll, true
+}
+
+// Url params read by the routes, these are never used as query param filters
+var reservedParams = map[string]bool{
+	"database":       true,
+	"collection":     true,
+	"limit":          true,
+	"skip":           true,
+	"withTotal":      true,
+	"readPreference": true,
+	"projection":     true,
+	"sort":           true,
+	"after":          true,
+	"from":           true,
+	"to":             true,
+	"allowDiskUse":   true,
+	"format":         true,
+	"select":         true,
+	"columns":        true,
+	"debug":          true,
+	"download":       true,
+	"batchSize":      true,
+	"pageSize":       true,
+	"cursorToken":    true,
+	"ordered":        true,
+	"confirm":        true,
+	"field":          true,
+	"timeField":      true,
+	"interval":       true,
+	"agg":            true,
+	"value":          true,
+	"maxTimeMS":      true,
+	"timing":         true,
+	"readConcern":    true,
+	"groupField":     true,
+	"scalar":         true,
+	"splitFacets":    true,
+	"facet":          true,
+}
+
+// Returns the filter with the query param filters of the request added and true.
+// If query param filters are enabled, url params other than the route params become equality matches,
+// ex) ?UserName=Jon adds {"UserName": "Jon"}. Repeated params become an $in match,
+// ex) ?Status=a&Status=b adds {"Status": {"$in": ["a", "b"]}}.
+// Values are converted to the type hint of the field if the collection has one.
+// If a value can't be converted an error response is sent and false is returned.
+func (s *server) paramFilter(ctx *gin.Context, collName string, filter bson.M) (bson.M, bool) {
+
+	if !s.queryParamFilters {
+		return filter, true
+	}
+
+	for field, values := range ctx.Request.URL.Query() {
+		if reservedParams[field] || len(values) == 0 {
+			continue
+		}
+
+		coerced := make(bson.A, len(values))
+		for i, value := range values {
+			var err error
+			coerced[i], err = coerceParam(value, s.paramTypeHints[collName][field])
+			if err != nil {
+				s.sendError(ctx, http.StatusBadRequest, "Param '%s' %s", field, err.Error())
+				return nil, false
+			}
+		}
+
+		if filter == nil {
+			filter = bson.M{}
+		}
+		if len(coerced) == 1 {
+			filter[field] = coerced[0]
+		} else {
+			filter[field] = bson.M{"$in": coerced}
+		}
+	}
+
+	return filter, true
+}
+
+// Returns the param value converted to the type hint, one of int, float, bool or date.
+// Dates are RFC 3339 timestamps or YYYY-MM-DD days. Values without a type hint are kept as strings.
+func coerceParam(value, typeHint string) (interface{}, error) {
+
+	switch typeHint {
+	case "":
+		return value, nil
+	case "int":
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("is not an int: %s", value)
+		}
+		return i, nil
+	case "float":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("is not a float: %s", value)
+		}
+		return f, nil
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("is not a bool: %s", value)
+		}
+		return b, nil
+	case "date":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("is not an RFC 3339 date: %s", value)
+		}
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("has unknown type hint '%s'", typeHint)
+}
+
+// Returns the time range filter on the time field and true.
+// The 'from' and 'to' url params are RFC 3339 dates or epoch milliseconds, like Grafana sends, either can be left out.
+// Nil is returned if no time field is set or neither param is passed.
+// If a param is invalid an error response is sent and false is returned.
+func (s *server) getTimeRange(ctx *gin.Context) (bson.M, bool) {
+
+	if s.timeField == "" {
+		return nil, true
+	}
+
+	timeRange := bson.M{}
+	for param, operator := range map[string]string{"from": "$gte", "to": "$lte"} {
+		value, ok := ctx.GetQuery(param)
+		if !ok {
+			continue
+		}
+
+		t, err := parseTime(value)
+		if err != nil {
+			s.sendError(ctx, http.StatusBadRequest, "Param '%s' is not an RFC 3339 date or epoch milliseconds: %s", param, value)
+			return nil, false
+		}
+		timeRange[operator] = t
+	}
+
+	if len(timeRange) == 0 {
+		return nil, true
+	}
+
+	return bson.M{s.timeField: timeRange}, true
+}
+
+// Returns the time of an RFC 3339 date or epoch milliseconds
+func parseTime(value string) (time.Time, error) {
+
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
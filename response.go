@@ -0,0 +1,568 @@
+package gomongoapi
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Output formats of the find and aggregate routes
+const (
+	formatJSON     = "json"
+	formatNDJSON   = "ndjson"
+	formatCSV      = "csv"
+	formatGeoJSON  = "geojson"
+	formatTable    = "table"
+	formatInfinity = "infinity"
+)
+
+// Media types of the Accept header and the output format they pick
+var acceptFormats = map[string]string{
+	"application/json":     formatJSON,
+	"application/x-ndjson": formatNDJSON,
+	"text/csv":             formatCSV,
+	"application/geo+json": formatGeoJSON,
+}
+
+// Number of documents sampled to get the columns of a streamed CSV, and number of rows written between flushes
+const (
+	csvSampleSize = 100
+	csvFlushRows  = 1000
+)
+
+// Header set when results are truncated to the hard result cap
+const truncatedHeader = "X-Results-Truncated"
+
+// Trailer holding the error that stopped a streamed response after its status was sent
+const streamErrorTrailer = "X-Stream-Error"
+
+// Sends a successful response.
+// If the response envelope is enabled, data is wrapped as {"status": "ok", "data": data}
+func (s *server) sendResult(ctx *gin.Context, code int, data interface{}) {
+	if s.responseEnvelope {
+		ctx.JSON(code, bson.M{"status": "ok", "data": data})
+		return
+	}
+
+	ctx.JSON(code, data)
+}
+
+// Sends an error response.
+// If the response envelope is enabled, the message is wrapped as {"status": "error", "message": message}
+func (s *server) sendError(ctx *gin.Context, code int, format string, values ...interface{}) {
+	if s.responseEnvelope {
+		ctx.JSON(code, bson.M{"status": "error", "message": fmt.Sprintf(format, values...)})
+		return
+	}
+
+	ctx.String(code, format, values...)
+}
+
+// Sends an error response with extra details describing the error.
+// Without the envelope the response is {"Error": message, <details>...}.
+// If the response envelope is enabled, it is wrapped as {"status": "error", "message": message, "details": details}
+func (s *server) sendErrorDetails(ctx *gin.Context, code int, message string, details bson.M) {
+	if s.responseEnvelope {
+		ctx.JSON(code, bson.M{"status": "error", "message": message, "details": details})
+		return
+	}
+
+	res := bson.M{"Error": message}
+	for k, v := range details {
+		res[k] = v
+	}
+
+	ctx.JSON(code, res)
+}
+
+// Returns true if the format is a known output format
+func validFormat(format string) bool {
+	switch format {
+	case formatJSON, formatNDJSON, formatCSV, formatGeoJSON, formatTable, formatInfinity:
+		return true
+	}
+	return false
+}
+
+// Returns the output format of the request.
+// The 'format' url param wins, then the first media type of the Accept header that picks a format, then the server default.
+// A JSON Accept header keeps a table default since tables are sent as JSON.
+func (s *server) getFormat(ctx *gin.Context) string {
+
+	if format, ok := ctx.GetQuery("format"); ok {
+		return format
+	}
+
+	for _, mediaType := range strings.Split(ctx.GetHeader("Accept"), ",") {
+		mediaType = strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0])
+		format, ok := acceptFormats[mediaType]
+		if !ok {
+			continue
+		}
+		if format == formatJSON && s.defaultFormat == formatTable {
+			break
+		}
+		return format
+	}
+
+	if s.defaultFormat == "" {
+		return formatJSON
+	}
+	return s.defaultFormat
+}
+
+// Returns the documents truncated to the hard result cap.
+// If they are truncated the X-Results-Truncated: true header is set.
+func (s *server) capResults(ctx *gin.Context, docs []bson.D) []bson.D {
+	if s.hardResultCap <= 0 || len(docs) <= s.hardResultCap {
+		return docs
+	}
+
+	ctx.Header(truncatedHeader, "true")
+	return docs[:s.hardResultCap]
+}
+
+// Sends the result documents of a find or aggregate with the status code.
+// The 'format' url param or the Accept header picks the output format, one of json, ndjson, csv, geojson, table or infinity,
+// and the server default format is used otherwise.
+// The 'download' url param sets the file name the response is downloaded as.
+// The 'select' url param is an expression in the server select language picking values out of the results.
+// The 'scalar' url param is a field of the only result sent as a bare JSON value.
+func (s *server) sendDocuments(ctx *gin.Context, code int, docs []bson.D) {
+
+	// No content responses can't have a body
+	if code == http.StatusNoContent {
+		ctx.Status(code)
+		return
+	}
+
+	format := s.getFormat(ctx)
+	if !validFormat(format) {
+		s.sendError(ctx, http.StatusBadRequest, "Format must be one of json, ndjson, csv, geojson, table or infinity")
+		return
+	}
+
+	docs = s.capResults(ctx, docs)
+
+	// Only JSON results can be sent as a scalar or reshaped
+	if !s.checkJSONOnlyParams(ctx, format) {
+		return
+	}
+
+	// Send a single value of the only result, ex) for single stat panels
+	if field, ok := ctx.GetQuery("scalar"); ok {
+		s.sendScalar(ctx, code, docs, field)
+		return
+	}
+
+	// Reshape the results with the select expression
+	if expr, ok := ctx.GetQuery("select"); ok {
+		s.sendSelected(ctx, code, docs, expr)
+		return
+	}
+
+	setDownloadHeader(ctx)
+
+	switch format {
+	case formatNDJSON:
+		s.sendNDJSON(ctx, code, docs)
+	case formatCSV:
+		s.sendCSV(ctx, code, docs)
+	case formatGeoJSON:
+		s.sendGeoJSON(ctx, code, docs)
+	case formatTable:
+		s.sendTable(ctx, code, docs)
+	case formatInfinity:
+		s.sendInfinity(ctx, code, docs)
+	default:
+		s.sendResult(ctx, code, s.jsonDocuments(docs))
+	}
+}
+
+// Returns true if the 'scalar' and 'select' url params aren't passed or the format is json.
+// Otherwise a bad request response is sent and false is returned.
+func (s *server) checkJSONOnlyParams(ctx *gin.Context, format string) bool {
+
+	if format == formatJSON {
+		return true
+	}
+
+	if _, ok := ctx.GetQuery("scalar"); ok {
+		s.sendError(ctx, http.StatusBadRequest, "Scalar can only be used with the json format")
+		return false
+	}
+	if _, ok := ctx.GetQuery("select"); ok {
+		s.sendError(ctx, http.StatusBadRequest, "Select can only be used with the json format")
+		return false
+	}
+
+	return true
+}
+
+// Sends the value of the field of the only document as a bare JSON value, without the response envelope.
+// If there isn't exactly one document or it doesn't have the field, a bad request response is sent.
+func (s *server) sendScalar(ctx *gin.Context, code int, docs []bson.D, field string) {
+
+	if len(docs) != 1 {
+		s.sendError(ctx, http.StatusBadRequest, "Scalar needs exactly one result, got %d", len(docs))
+		return
+	}
+
+	value, ok := lookupField(docs[0], field)
+	if !ok {
+		s.sendError(ctx, http.StatusBadRequest, "Result has no field %s", field)
+		return
+	}
+
+	ctx.JSON(code, s.jsonValue(value))
+}
+
+// Sends the documents as newline delimited JSON, one document per line
+func (s *server) sendNDJSON(ctx *gin.Context, code int, docs []bson.D) {
+
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Status(code)
+
+	encoder := json.NewEncoder(ctx.Writer)
+	for _, doc := range docs {
+		if err := encoder.Encode(s.jsonValue(doc)); err != nil {
+			return
+		}
+	}
+}
+
+// Sends the documents as CSV with a header row.
+// Columns are the top level fields of all documents in the order they are first seen, nested values are written as JSON.
+func (s *server) sendCSV(ctx *gin.Context, code int, docs []bson.D) {
+
+	columns, columnIndexes := documentColumns(docs)
+
+	ctx.Header("Content-Type", "text/csv")
+	ctx.Status(code)
+
+	writer := csv.NewWriter(ctx.Writer)
+	if err := writer.Write(columns); err != nil {
+		return
+	}
+
+	row := make([]string, len(columns))
+	for _, doc := range docs {
+		for i := range row {
+			row[i] = ""
+		}
+		for _, elem := range doc {
+			row[columnIndexes[elem.Key]] = csvValue(s.jsonValue(elem.Value))
+		}
+		if err := writer.Write(row); err != nil {
+			return
+		}
+	}
+
+	writer.Flush()
+}
+
+// Sends the documents as a table in the Grafana JSON datasource format,
+// [{"type": "table", "columns": [{"text": column}...], "rows": [[value...]...]}].
+// Columns are the top level fields of all documents in the order they are first seen, missing fields are null.
+func (s *server) sendTable(ctx *gin.Context, code int, docs []bson.D) {
+
+	columns, columnIndexes := documentColumns(docs)
+
+	tableColumns := make([]bson.M, len(columns))
+	for i, column := range columns {
+		tableColumns[i] = bson.M{"text": column}
+	}
+
+	rows := make([][]interface{}, len(docs))
+	for i, doc := range docs {
+		rows[i] = make([]interface{}, len(columns))
+		for _, elem := range doc {
+			rows[i][columnIndexes[elem.Key]] = s.jsonValue(elem.Value)
+		}
+	}
+
+	s.sendResult(ctx, code, []bson.M{{"type": "table", "columns": tableColumns, "rows": rows}})
+}
+
+// Sends the documents in the shape the Grafana Infinity plugin JSON parser reads most reliably,
+// a root level array of flat objects, [{"Name": "Jon", "Age": 30, "Address": "{\"City\":\"Paris\"}"}...].
+// Top level fields keep their order, nested documents and arrays are written as JSON strings and other values as is.
+// The array is always the root, the response envelope is not applied.
+func (s *server) sendInfinity(ctx *gin.Context, code int, docs []bson.D) {
+
+	rows := make([]orderedDocument, len(docs))
+	for i, doc := range docs {
+		row := make(orderedDocument, len(doc))
+		for j, elem := range doc {
+			value := s.jsonValue(elem.Value)
+			switch value.(type) {
+			case orderedDocument, map[string]interface{}, bson.M, bson.A, []interface{}:
+				b, err := json.Marshal(value)
+				if err != nil {
+					s.sendError(ctx, http.StatusInternalServerError, "Error encoding results: %s", err.Error())
+					return
+				}
+				value = string(b)
+			}
+			row[j] = bson.E{Key: elem.Key, Value: value}
+		}
+		rows[i] = row
+	}
+
+	ctx.JSON(code, rows)
+}
+
+// Returns the top level fields of the documents in the order they are first seen and the index of each field
+func documentColumns(docs []bson.D) ([]string, map[string]int) {
+
+	var columns []string
+	columnIndexes := make(map[string]int)
+	for _, doc := range docs {
+		for _, elem := range doc {
+			if _, ok := columnIndexes[elem.Key]; !ok {
+				columnIndexes[elem.Key] = len(columns)
+				columns = append(columns, elem.Key)
+			}
+		}
+	}
+
+	return columns, columnIndexes
+}
+
+// Streams the documents of the cursor as CSV with a header row and closes it, rows are written as they are read.
+// Columns are the comma separated 'columns' url param, or else the top level fields of the first documents in the order
+// they are first seen. Sampling avoids buffering all documents, but fields only found after the sample are left out,
+// so pass 'columns' when documents have varied fields. Rows stop at the hard result cap.
+// The status and headers are set from the sample. Once rows past the sample are written they can't change, so a
+// failure reading them is sent in the X-Stream-Error trailer, and truncation or a timeout found then in the
+// X-Results-Truncated and X-Partial-Results trailers.
+// The 'scalar' and 'select' url params can't be used with CSV, a bad request response is sent if they are passed.
+func (s *server) streamCSV(ctx *gin.Context, cursor *mongo.Cursor) {
+
+	defer cursor.Close(context.Background())
+	reqCtx := ctx.Request.Context()
+
+	if !s.checkJSONOnlyParams(ctx, formatCSV) {
+		return
+	}
+
+	// Read the sample documents, they are also the first rows.
+	// With a hard result cap under the sample size one more document is read to know if rows are truncated.
+	sampleSize := csvSampleSize
+	if s.hardResultCap > 0 && s.hardResultCap < sampleSize {
+		sampleSize = s.hardResultCap + 1
+	}
+	var sample []bson.D
+	for len(sample) < sampleSize && cursor.Next(reqCtx) {
+		var doc bson.D
+		if err := cursor.Decode(&doc); err != nil {
+			s.sendError(ctx, http.StatusInternalServerError, "Error decoding results: %s", err.Error())
+			return
+		}
+		sample = append(sample, doc)
+	}
+	partial := false
+	if err := cursor.Err(); err != nil {
+		if !(s.returnPartialOnTimeout && isTimeoutError(err)) {
+			s.sendError(ctx, http.StatusInternalServerError, "Error decoding results: %s", err.Error())
+			return
+		}
+		partial = true
+	}
+	truncated := s.hardResultCap > 0 && len(sample) > s.hardResultCap
+	if truncated {
+		sample = sample[:s.hardResultCap]
+	}
+	s.encodeResults(sample)
+	s.transformResults(ctx, sample)
+
+	code := http.StatusOK
+	if len(sample) == 0 && !partial {
+		code = s.emptyResultStatus
+	}
+	if code == http.StatusNoContent {
+		ctx.Status(code)
+		return
+	}
+	if partial {
+		ctx.Header(partialResultsHeader, "true")
+		code = http.StatusPartialContent
+	}
+	if truncated {
+		ctx.Header(truncatedHeader, "true")
+	}
+
+	var columns []string
+	if param := ctx.Query("columns"); param != "" {
+		for _, column := range strings.Split(param, ",") {
+			columns = append(columns, strings.TrimSpace(column))
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, doc := range sample {
+			for _, elem := range doc {
+				if !seen[elem.Key] {
+					seen[elem.Key] = true
+					columns = append(columns, elem.Key)
+				}
+			}
+		}
+	}
+	columnIndexes := make(map[string]int, len(columns))
+	for i, column := range columns {
+		columnIndexes[column] = i
+	}
+
+	// Rows past the sample are only read if the sample didn't already end the results
+	streaming := !partial && !truncated && len(sample) == sampleSize
+	if streaming {
+		trailers := []string{streamErrorTrailer}
+		if s.hardResultCap > 0 {
+			trailers = append(trailers, truncatedHeader)
+		}
+		if s.returnPartialOnTimeout {
+			trailers = append(trailers, partialResultsHeader)
+		}
+		ctx.Header("Trailer", strings.Join(trailers, ", "))
+	}
+
+	setDownloadHeader(ctx)
+	ctx.Header("Content-Type", "text/csv")
+	ctx.Status(code)
+
+	writer := csv.NewWriter(ctx.Writer)
+	if err := writer.Write(columns); err != nil {
+		return
+	}
+
+	row := make([]string, len(columns))
+	writeRow := func(doc bson.D) error {
+		for i := range row {
+			row[i] = ""
+		}
+		for _, elem := range doc {
+			if i, ok := columnIndexes[elem.Key]; ok {
+				row[i] = csvValue(s.jsonValue(elem.Value))
+			}
+		}
+		return writer.Write(row)
+	}
+
+	for _, doc := range sample {
+		if err := writeRow(doc); err != nil {
+			return
+		}
+	}
+	if !streaming {
+		writer.Flush()
+		return
+	}
+
+	rows := len(sample)
+	var streamErr error
+	for cursor.Next(reqCtx) {
+		if s.hardResultCap > 0 && rows >= s.hardResultCap {
+			truncated = true
+			break
+		}
+
+		var doc bson.D
+		if err := cursor.Decode(&doc); err != nil {
+			streamErr = err
+			break
+		}
+		docs := []bson.D{doc}
+		s.encodeResults(docs)
+		s.transformResults(ctx, docs)
+		if err := writeRow(docs[0]); err != nil {
+			return
+		}
+		rows++
+
+		// Flush regularly so rows reach the client as they are written
+		if rows%csvFlushRows == 0 {
+			writer.Flush()
+			ctx.Writer.Flush()
+		}
+	}
+	if err := cursor.Err(); err != nil && streamErr == nil {
+		if s.returnPartialOnTimeout && isTimeoutError(err) {
+			partial = true
+		} else {
+			streamErr = err
+		}
+	}
+
+	writer.Flush()
+
+	// Trailers are sent after the body
+	if streamErr != nil {
+		log.Printf("Error streaming CSV results: %s\n", streamErr.Error())
+		ctx.Writer.Header().Set(streamErrorTrailer, streamErr.Error())
+	}
+	if truncated {
+		ctx.Writer.Header().Set(truncatedHeader, "true")
+	}
+	if partial {
+		ctx.Writer.Header().Set(partialResultsHeader, "true")
+	}
+}
+
+// Sets the Content-Disposition header if the 'download' url param sets the file name the response is downloaded as
+func setDownloadHeader(ctx *gin.Context) {
+	if fileName, ok := ctx.GetQuery("download"); ok {
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, sanitizeFileName(fileName)))
+	}
+}
+
+// Returns the CSV cell of a value, strings are written as is and everything else as JSON
+func csvValue(value interface{}) string {
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	// Values like dates marshal to JSON strings, write them without quotes
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		return str
+	}
+
+	return string(b)
+}
+
+// Returns the file name with everything but letters, digits, dots, dashes and underscores replaced.
+// This prevents header injection through the Content-Disposition header.
+func sanitizeFileName(fileName string) string {
+
+	fileName = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, fileName)
+
+	fileName = strings.TrimLeft(fileName, ".")
+	if fileName == "" {
+		return "export"
+	}
+
+	return fileName
+}
@@ -0,0 +1,119 @@
+package gomongoapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Streams a cursor of the documents, ended by the error, as CSV and returns the response
+func streamTestCSV(t *testing.T, s *server, count int, cursorErr error) *http.Response {
+
+	docs := make([]interface{}, count)
+	for i := range docs {
+		docs[i] = bson.D{{Key: "n", Value: i}}
+	}
+	cursor, err := mongo.NewCursorFromDocuments(docs, cursorErr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/?format=csv", nil)
+	s.streamCSV(ctx, cursor)
+	ctx.Writer.WriteHeaderNow()
+
+	return rec.Result()
+}
+
+// Returns the number of rows of the CSV response, without the header row
+func csvRows(t *testing.T, res *http.Response) int {
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(body), "\n") - 1
+}
+
+func TestStreamCSV(t *testing.T) {
+
+	t.Run("truncated in sample", func(t *testing.T) {
+		res := streamTestCSV(t, &server{hardResultCap: 5, emptyResultStatus: http.StatusOK}, 10, nil)
+		if res.StatusCode != http.StatusOK || res.Header.Get(truncatedHeader) != "true" {
+			t.Errorf("expected 200 with truncated header, got %d %v", res.StatusCode, res.Header)
+		}
+		if rows := csvRows(t, res); rows != 5 {
+			t.Errorf("expected 5 rows, got %d", rows)
+		}
+	})
+
+	t.Run("truncated after sample", func(t *testing.T) {
+		res := streamTestCSV(t, &server{hardResultCap: csvSampleSize + 50, emptyResultStatus: http.StatusOK}, csvSampleSize+100, nil)
+		if rows := csvRows(t, res); rows != csvSampleSize+50 {
+			t.Errorf("expected %d rows, got %d", csvSampleSize+50, rows)
+		}
+		if res.Trailer.Get(truncatedHeader) != "true" {
+			t.Errorf("expected truncated trailer, got %v", res.Trailer)
+		}
+		if res.Trailer.Get(streamErrorTrailer) != "" {
+			t.Errorf("expected no stream error, got %s", res.Trailer.Get(streamErrorTrailer))
+		}
+	})
+
+	t.Run("not truncated", func(t *testing.T) {
+		res := streamTestCSV(t, &server{hardResultCap: csvSampleSize + 50, emptyResultStatus: http.StatusOK}, csvSampleSize+50, nil)
+		if rows := csvRows(t, res); rows != csvSampleSize+50 {
+			t.Errorf("expected %d rows, got %d", csvSampleSize+50, rows)
+		}
+		if res.Header.Get(truncatedHeader) != "" || res.Trailer.Get(truncatedHeader) != "" {
+			t.Errorf("expected results not to be truncated")
+		}
+	})
+
+	t.Run("empty result status", func(t *testing.T) {
+		res := streamTestCSV(t, &server{emptyResultStatus: http.StatusNotFound}, 0, nil)
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, res.StatusCode)
+		}
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		res := streamTestCSV(t, &server{returnPartialOnTimeout: true, emptyResultStatus: http.StatusOK}, 0, context.DeadlineExceeded)
+		if res.StatusCode != http.StatusPartialContent || res.Header.Get(partialResultsHeader) != "true" {
+			t.Errorf("expected 206 with partial header, got %d %v", res.StatusCode, res.Header)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		res := streamTestCSV(t, &server{emptyResultStatus: http.StatusOK}, 0, context.Canceled)
+		if res.StatusCode != http.StatusInternalServerError {
+			t.Errorf("expected status %d, got %d", http.StatusInternalServerError, res.StatusCode)
+		}
+	})
+}
+
+func TestStreamCSVRejectsJSONOnlyParams(t *testing.T) {
+
+	for _, param := range []string{"scalar=n", "select=$.n"} {
+		t.Run(param, func(t *testing.T) {
+			cursor, err := mongo.NewCursorFromDocuments([]interface{}{bson.D{{Key: "n", Value: 1}}}, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, rec := newTestContext("/?format=csv&" + param)
+			(&server{}).streamCSV(ctx, cursor)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package gomongoapi
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Registers the type find and aggregate results of the collection are decoded into, ex) RegisterResultType("users", User{}).
+// Typed results skip the generic document decoding and are marshaled using the type's json tags, so the timestamp format,
+// id handling and field order options don't apply to them. They are only used for the json format.
+// Types must be registered before the server starts.
+func (s *server) RegisterResultType(collection string, prototype interface{}) {
+
+	typ := reflect.TypeOf(prototype)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	s.resultTypes[collection] = typ
+}
+
+// Returns the registered result type of the collection and true if the request results can be decoded into it,
+// they can't be when the request picks another format, a select expression or a scalar field
+func (s *server) getResultType(ctx *gin.Context, collName string) (reflect.Type, bool) {
+	typ, ok := s.resultTypes[collName]
+	if !ok || s.getFormat(ctx) != formatJSON || ctx.Query("select") != "" || ctx.Query("scalar") != "" {
+		return nil, false
+	}
+
+	return typ, true
+}
+
+// Decodes all documents of the cursor into the result type and sends them
+func (s *server) sendTypedResults(ctx *gin.Context, cursor *mongo.Cursor, typ reflect.Type) {
+
+	res, partial, err := s.readTypedCursor(ctx.Request.Context(), cursor, typ)
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error decoding results: %s", err.Error())
+		return
+	}
+
+	// Truncate to the hard result cap
+	if s.hardResultCap > 0 && res.Len() > s.hardResultCap {
+		ctx.Header(truncatedHeader, "true")
+		res = res.Slice(0, s.hardResultCap)
+	}
+
+	code := http.StatusOK
+	if res.Len() == 0 && !partial {
+		code = s.emptyResultStatus
+	}
+	if partial {
+		ctx.Header(partialResultsHeader, "true")
+		code = http.StatusPartialContent
+	}
+
+	// No content responses can't have a body
+	if code == http.StatusNoContent {
+		ctx.Status(code)
+		return
+	}
+
+	s.sendResult(ctx, code, res.Interface())
+}
+
+// Decodes all documents of the cursor into a slice of the result type and closes it.
+// Timeouts are handled the same as readCursor.
+func (s *server) readTypedCursor(ctx context.Context, cursor *mongo.Cursor, typ reflect.Type) (reflect.Value, bool, error) {
+
+	defer cursor.Close(context.Background())
+
+	docs := reflect.MakeSlice(reflect.SliceOf(typ), 0, 0)
+	for cursor.Next(ctx) {
+		doc := reflect.New(typ)
+		if err := cursor.Decode(doc.Interface()); err != nil {
+			return reflect.Value{}, false, err
+		}
+		docs = reflect.Append(docs, doc.Elem())
+	}
+
+	if err := cursor.Err(); err != nil {
+		if s.returnPartialOnTimeout && isTimeoutError(err) {
+			return docs, true, nil
+		}
+		return reflect.Value{}, false, err
+	}
+
+	return docs, false, nil
+}
@@ -0,0 +1,72 @@
+package gomongoapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Base wait time between retries, it is multiplied by the attempt number
+const retryBackoff = 100 * time.Millisecond
+
+// Runs the operation and re-issues it up to the server max retries when it fails with a retryable error.
+// Stops early if the context is done while waiting for the next attempt.
+// Retries stack on top of the driver retryable reads, which already retries a failed read once before it is returned here.
+func (s *server) withRetry(ctx context.Context, op func() error) error {
+
+	err := op()
+	for attempt := 1; attempt <= s.maxRetries && isRetryableError(err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * retryBackoff):
+		}
+
+		err = op()
+	}
+
+	return err
+}
+
+// Server error codes of read errors caused by topology changes, the same codes the driver retries reads on
+var retryableReadCodes = []int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	134,   // ReadConcernMajorityNotAvailableYet
+	189,   // PrimarySteppedDown
+	262,   // ExceededTimeLimit
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// Returns true if the error is a network error, a read error caused by a topology change, or labeled as retryable by the server.
+// Handlers only run reads outside of transactions, so most errors are classified by their code rather than by label.
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		for _, code := range retryableReadCodes {
+			if serverErr.HasErrorCode(code) {
+				return true
+			}
+		}
+		return serverErr.HasErrorLabel("TransientTransactionError") || serverErr.HasErrorLabel("RetryableWriteError")
+	}
+
+	return false
+}
@@ -0,0 +1,46 @@
+package gomongoapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestIsRetryableError(t *testing.T) {
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"not writable primary", mongo.CommandError{Code: 10107, Name: "NotWritablePrimary"}, true},
+		{"shutdown in progress", mongo.CommandError{Code: 91, Name: "ShutdownInProgress"}, true},
+		{"transient transaction", mongo.CommandError{Code: 251, Labels: []string{"TransientTransactionError"}}, true},
+		{"bad value", mongo.CommandError{Code: 2, Name: "BadValue"}, false},
+		{"other", errors.New("other"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableError(tt.err); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+
+	s := &server{maxRetries: 2}
+	attempts := 0
+	err := s.withRetry(context.Background(), func() error {
+		attempts++
+		return mongo.CommandError{Code: 189, Name: "PrimarySteppedDown"}
+	})
+	if err == nil || attempts != 3 {
+		t.Errorf("expected 3 attempts ending in an error, got %d attempts and %v", attempts, err)
+	}
+}
@@ -0,0 +1,100 @@
+package gomongoapi
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Route exposed by the server
+type RouteInfo struct {
+	Method string
+	Path   string
+
+	// True if the route is created by the server, false if it was added by the user, ex) with AddCustomGET()
+	Builtin bool
+}
+
+// Returns every route registered on the router, built-in routes are only registered once the server starts
+func (s *server) Routes() []RouteInfo {
+
+	builtin := make(map[string]bool)
+	for _, r := range s.builtinRoutes() {
+		builtin[r.method+" "+r.path] = true
+	}
+
+	routes := s.router.Routes()
+	res := make([]RouteInfo, len(routes))
+	for i, r := range routes {
+		res[i] = RouteInfo{
+			Method:  r.Method,
+			Path:    r.Path,
+			Builtin: builtin[r.Method+" "+r.Path],
+		}
+	}
+
+	return res
+}
+
+// Route to list every route exposed by the server
+// /api/routes
+func (s *server) getRoutes(c *gin.Context) {
+
+	res := bson.M{
+		"Routes": s.Routes(),
+	}
+
+	s.sendResult(c, http.StatusOK, res)
+}
+
+// Handles requests with a method the path has no route for, responds 405 with the Allow header listing the methods it has
+func (s *server) methodNotAllowed(ctx *gin.Context) {
+
+	var methods []string
+	for _, r := range s.router.Routes() {
+		if matchRoute(r.Path, ctx.Request.URL.Path) && !containsString(methods, r.Method) {
+			methods = append(methods, r.Method)
+		}
+	}
+	sort.Strings(methods)
+
+	ctx.Header("Allow", strings.Join(methods, ", "))
+	s.sendErrorDetails(ctx, http.StatusMethodNotAllowed, "Method "+ctx.Request.Method+" is not allowed", bson.M{
+		"Allow": methods,
+	})
+}
+
+// Returns true if the path matches the route template, ex) /api/collections/:name/find
+func matchRoute(template, path string) bool {
+
+	templateParts := strings.Split(strings.Trim(template, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, part := range templateParts {
+		if strings.HasPrefix(part, "*") {
+			return true
+		}
+		if i >= len(pathParts) {
+			return false
+		}
+		if !strings.HasPrefix(part, ":") && part != pathParts[i] {
+			return false
+		}
+	}
+
+	return len(templateParts) == len(pathParts)
+}
+
+// Returns true if the value is in the values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,99 @@
+package gomongoapi
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Result of the self test query on a collection
+type selfTestResult struct {
+	Collection string
+	Ok         bool
+	Duration   string
+	Error      string `json:",omitempty"`
+}
+
+// Route to check every collection is reachable and queryable, runs a limit 1 find per collection.
+// Collections are the ones listed by /api/collections, sorted by name. Finds use the collection read preference and
+// the find route timeout, so they probe what the find route serves.
+// Returns 200 if all succeed, otherwise 503 with the failed collections.
+// /api/selftest?database=app
+func (s *server) getSelfTest(c *gin.Context) {
+
+	// Get database name, return error if one isn't available
+	dbName, ok := s.getDatabaseName(c)
+	if !ok {
+		return
+	}
+
+	// Get collections to test
+	collNames, err := s.listCollectionNames(c.Request.Context(), dbName)
+	if err != nil {
+		s.sendError(c, http.StatusServiceUnavailable, "Error getting collection names: %s", err.Error())
+		return
+	}
+	sort.Strings(collNames)
+
+	timeout := s.getOperationTimeout("find")
+	results := make([]selfTestResult, len(collNames))
+	var failed []string
+	for i, collName := range collNames {
+		start := time.Now()
+
+		// Get collection handle with the request read preference
+		coll, ok := s.getCollection(c, dbName, collName)
+		if !ok {
+			return
+		}
+
+		err := selfTestFind(c.Request.Context(), coll, timeout)
+
+		results[i] = selfTestResult{
+			Collection: collName,
+			Ok:         err == nil,
+			Duration:   time.Since(start).String(),
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			failed = append(failed, collName)
+		}
+	}
+
+	if len(failed) > 0 {
+		s.sendErrorDetails(c, http.StatusServiceUnavailable, "Self test failed", bson.M{
+			"Failed":      failed,
+			"Collections": results,
+		})
+		return
+	}
+
+	res := bson.M{
+		"Collections": results,
+	}
+
+	s.sendResult(c, http.StatusOK, res)
+}
+
+// Runs a limit 1 find on the collection bounded by the timeout, if one is set
+func selfTestFind(ctx context.Context, coll *mongo.Collection, timeout time.Duration) error {
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetLimit(1))
+	if err != nil {
+		return err
+	}
+
+	return cursor.Close(ctx)
+}
@@ -0,0 +1,167 @@
+package gomongoapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// Returns a server with its routes created but not connected to MongoDB.
+// Only requests rejected before reaching the database can be served.
+func newTestServer(opts *Options) *server {
+	opts.SetRouter(gin.New())
+	s := NewServer(opts).(*server)
+	s.createRoutes()
+	return s
+}
+
+// Sends the request to the server and returns the recorded response
+func serveTest(s *server, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAggregateRejectsDisallowedCollections(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetAllowedCollections([]string{"users", "orders"})
+	s := newTestServer(opts)
+
+	tests := []struct {
+		name     string
+		pipeline string
+	}{
+		{"unionWith", `[{"$unionWith": "secrets"}]`},
+		{"unionWith coll", `[{"$unionWith": {"coll": "secrets", "pipeline": []}}]`},
+		{"lookup", `[{"$lookup": {"from": "secrets", "localField": "a", "foreignField": "b", "as": "c"}}]`},
+		{"nested lookup", `[{"$lookup": {"from": "orders", "as": "c", "pipeline": [{"$unionWith": "secrets"}]}}]`},
+		{"facet", `[{"$facet": {"a": [{"$lookup": {"from": "secrets", "as": "c"}}]}}]`},
+		{"graphLookup", `[{"$graphLookup": {"from": "secrets", "startWith": "$a", "connectFromField": "a", "connectToField": "b", "as": "c"}}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveTest(s, http.MethodPost, "/api/collections/users/aggregate", `{"Aggregate": `+tt.pipeline+`}`)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), "secrets") {
+				t.Errorf("expected error to name the collection, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReferencedCollections(t *testing.T) {
+
+	var pipeLine []interface{}
+	err := json.Unmarshal([]byte(`[
+		{"$match": {"a": 1}},
+		{"$lookup": {"from": "a", "as": "x", "pipeline": [{"$unionWith": {"coll": "b", "pipeline": [{"$lookup": {"from": "c", "as": "y"}}]}}]}},
+		{"$graphLookup": {"from": "d", "startWith": "$a", "connectFromField": "a", "connectToField": "b", "as": "z"}},
+		{"$facet": {
+			"one": [{"$unionWith": "e"}],
+			"two": [{"$graphLookup": {"from": "f", "startWith": "$a", "connectFromField": "a", "connectToField": "b", "as": "z"}}]
+		}}
+	]`), &pipeLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := referencedCollections(pipeLine)
+	sort.Strings(got)
+	want := []string{"a", "b", "c", "d", "e", "f"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestZeroValueOptions(t *testing.T) {
+
+	opts := &Options{}
+	opts.SetRouter(gin.New())
+	s := NewServer(opts).(*server)
+
+	if !s.allowDiskUse {
+		t.Error("expected disk use to be allowed")
+	}
+	if !s.exposeDatabaseList {
+		t.Error("expected database list to be exposed")
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected zero value options to be valid, got %s", err)
+	}
+
+	opts = ServerOptions()
+	opts.SetAllowDiskUse(false)
+	opts.SetExposeDatabaseList(false)
+	opts.SetRouter(gin.New())
+	s = NewServer(opts).(*server)
+	if s.allowDiskUse || s.exposeDatabaseList {
+		t.Error("expected disk use and the database list to be disabled")
+	}
+}
+
+func TestListCollectionNames(t *testing.T) {
+
+	cached := []string{"users", "system.views", "orders", "secrets"}
+	s := &server{metadataCache: &metadataCache{ttl: time.Minute, entries: map[string]metadataEntry{
+		"app": {names: cached, expires: time.Now().Add(time.Minute)},
+	}}}
+
+	// System collections are hidden unless they are included
+	got, err := s.listCollectionNames(context.Background(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"users", "orders", "secrets"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Only allowed collections are listed, and sorting them doesn't change the cached names
+	s.allowedCollections = map[string]bool{"users": true, "orders": true}
+	got, _ = s.listCollectionNames(context.Background(), "app")
+	sort.Strings(got)
+	if want := []string{"orders", "users"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if cached[0] != "users" {
+		t.Errorf("expected the cached names to be kept, got %v", cached)
+	}
+}
+
+func TestAddDatabaseNamespace(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetRouter(gin.New())
+	s := NewServer(opts).(*server)
+
+	for _, prefix := range []string{"/databases", "/collections", "/variables", "/selftest", "/batch", "/stats", "selftest/app", "/:db", "/app*", ""} {
+		if err := s.AddDatabaseNamespace(prefix, "app"); err != ErrInvalidNamespace {
+			t.Errorf("expected prefix %q to be invalid, got %v", prefix, err)
+		}
+	}
+
+	if err := s.AddDatabaseNamespace("/reporting", "reports"); err != nil {
+		t.Fatalf("expected prefix to be valid, got %s", err)
+	}
+	if err := s.AddDatabaseNamespace("reporting/", "other"); err != ErrInvalidNamespace {
+		t.Errorf("expected duplicate prefix to be invalid, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package gomongoapi
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Returns the timeout of the operation, its route timeout if one is set, otherwise the operation timeout.
+// The watch and export operations only have a timeout if their route timeout is set, as they are meant to run long.
+func (s *server) getOperationTimeout(operation string) time.Duration {
+
+	if timeout, ok := s.routeTimeouts[operation]; ok {
+		return timeout
+	}
+	if operation == "watch" || operation == "export" {
+		return 0
+	}
+
+	return s.operationTimeout
+}
+
+// Returns middleware that bounds the request context of the collection route to the timeout of its operation.
+// Operations are the last segment of the route path, ex) find, aggregate.
+func (s *server) timeoutMiddleware(routePath string) gin.HandlerFunc {
+
+	timeout := s.getOperationTimeout(path.Base(routePath))
+	return func(ctx *gin.Context) {
+		if timeout <= 0 {
+			return
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		defer cancel()
+
+		ctx.Request = ctx.Request.WithContext(timeoutCtx)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,33 @@
+package gomongoapi
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers holding the duration of the mongo operation and of the whole handler, set on requests with 'timing=true'
+const (
+	queryDurationHeader   = "X-Query-Duration-Ms"
+	handlerDurationHeader = "X-Handler-Duration-Ms"
+)
+
+// Sets the timing headers if the request has 'timing=true'.
+// The query duration is the time since queryStart, the handler duration the time since handlerStart, both in milliseconds.
+// Headers can be set again to extend the durations as long as the response hasn't been written.
+func setTimingHeaders(ctx *gin.Context, handlerStart, queryStart time.Time) {
+
+	if ctx.Query("timing") != "true" {
+		return
+	}
+
+	now := time.Now()
+	ctx.Header(queryDurationHeader, formatMillis(now.Sub(queryStart)))
+	ctx.Header(handlerDurationHeader, formatMillis(now.Sub(handlerStart)))
+}
+
+// Returns the duration in milliseconds with microsecond precision, ex) 12.345
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', 3, 64)
+}
@@ -0,0 +1,47 @@
+package gomongoapi
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Time fields of time series collections keyed by "<db>.<collection>", empty for other collections
+type timeSeriesCache struct {
+	mu     sync.Mutex
+	fields map[string]string
+}
+
+// Returns the time field of the collection if it is a time series collection and the server is time series aware,
+// otherwise empty. Collection options are read once and cached, errors reading them are treated as a regular collection.
+func (s *server) collectionTimeField(ctx context.Context, dbName, collName string) string {
+
+	if !s.timeSeriesAware {
+		return ""
+	}
+
+	key := dbName + "." + collName
+	s.timeSeriesCollections.mu.Lock()
+	timeField, ok := s.timeSeriesCollections.fields[key]
+	s.timeSeriesCollections.mu.Unlock()
+	if ok {
+		return timeField
+	}
+
+	specs, err := s.mongoClient.Database(dbName).ListCollectionSpecifications(ctx, bson.M{"name": collName},
+		options.ListCollections().SetNameOnly(false))
+	if err != nil {
+		return ""
+	}
+	if len(specs) > 0 && specs[0].Type == "timeseries" && specs[0].Options != nil {
+		timeField, _ = specs[0].Options.Lookup("timeseries", "timeField").StringValueOK()
+	}
+
+	s.timeSeriesCollections.mu.Lock()
+	s.timeSeriesCollections.fields[key] = timeField
+	s.timeSeriesCollections.mu.Unlock()
+
+	return timeField
+}
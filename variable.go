@@ -0,0 +1,109 @@
+package gomongoapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Variable route returning the distinct values of a collection field
+type variableRoute struct {
+	name       string
+	collection string
+	field      string
+	filter     bson.M
+}
+
+// Registers a GET route under /api/variables/<name> returning the distinct values of the field of the collection
+// documents matching the filter as a JSON array of strings, the shape Grafana template variables expect.
+// Routes are created when the server starts.
+func (s *server) AddVariableRoute(name, collection, field string, filter bson.M) error {
+
+	name = strings.Trim(name, "/")
+	if name == "" || strings.ContainsAny(name, "/:*") || collection == "" || field == "" {
+		return ErrInvalidVariableRoute
+	}
+	for _, v := range s.variableRoutes {
+		if v.name == name {
+			return ErrInvalidVariableRoute
+		}
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	s.variableRoutes = append(s.variableRoutes, variableRoute{
+		name:       name,
+		collection: collection,
+		field:      strings.TrimPrefix(field, "$"),
+		filter:     filter,
+	})
+	return nil
+}
+
+// Returns the handler of the variable route.
+// Valid URL parameters are 'database' and 'readPreference'
+// Values are truncated to the server max distinct values, with the X-Truncated: true header set.
+// Values that aren't strings are sent as their JSON.
+//	ex) Result: ["eu-west", "us-east"]
+func (s *server) variableHandler(v variableRoute) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		// Get database name, return error if one isn't available
+		dbName, ok := s.getDatabaseName(ctx)
+		if !ok {
+			return
+		}
+
+		if !s.isCollectionAllowed(v.collection) {
+			s.sendError(ctx, http.StatusForbidden, "Collection %s is not allowed", v.collection)
+			return
+		}
+
+		// Scope the filter to the collection base filter and the tenant of the request
+		filter, ok := s.scopeFilter(ctx, v.collection, v.filter)
+		if !ok {
+			return
+		}
+
+		// Let the filter interceptor rewrite or reject the filter
+		filter, ok = s.interceptFilter(ctx, dbName, v.collection, filter)
+		if !ok {
+			return
+		}
+
+		s.setDebugQuery(ctx, bson.M{"field": v.field, "filter": filter})
+
+		// Get collection handle with the request read preference
+		coll, ok := s.getCollection(ctx, dbName, v.collection)
+		if !ok {
+			return
+		}
+
+		var values []interface{}
+		err := s.withRetry(ctx.Request.Context(), func() error {
+			var err error
+			values, err = coll.Distinct(ctx.Request.Context(), v.field, filter)
+			return err
+		})
+		if err != nil {
+			s.sendError(ctx, http.StatusInternalServerError, "Error running distinct: %s", err.Error())
+			return
+		}
+
+		if s.maxDistinctValues > 0 && len(values) > s.maxDistinctValues {
+			ctx.Header(distinctTruncatedHeader, "true")
+			values = values[:s.maxDistinctValues]
+		}
+
+		res := make([]string, len(values))
+		for i, value := range values {
+			res[i] = csvValue(s.jsonValue(s.encodeValue(value)))
+		}
+
+		ctx.JSON(http.StatusOK, res)
+	}
+}
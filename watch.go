@@ -0,0 +1,142 @@
+package gomongoapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Seconds clients are told to wait before retrying when the max number of change streams are open
+const changeStreamRetryAfter = 5
+
+// Longest time a change stream waits for events before checking on the client, and longest time between keepalives
+const (
+	changeStreamMaxAwait  = time.Second
+	changeStreamKeepalive = 15 * time.Second
+)
+
+// Streams the change events of the collection as server sent events. /collections/:name/watch
+// Valid URL parameters are 'database' and 'readPreference'
+// Each event is named change and has the change event as JSON, updates include the full document.
+// The event _id is its resume token and is always sent, id handling is applied to the full document.
+// The stream stays open until the client disconnects, so the response write timeout should be unset when it is used.
+// A keepalive comment is sent when there are no events, streams that can't be sent to within the cursor idle timeout are closed.
+// Collections with a base filter or tenant filter, or filtered by the filter interceptor, can't be watched as change events aren't scoped.
+func (s *server) collectionWatch(ctx *gin.Context) {
+
+	// Get database name, return error if one isn't available
+	dbName, ok := s.getDatabaseName(ctx)
+	if !ok {
+		return
+	}
+
+	// Get collection name, return error if one isn't passed or allowed
+	collName, ok := s.getCollectionName(ctx)
+	if !ok {
+		return
+	}
+
+	if s.isScoped(collName) {
+		s.sendError(ctx, http.StatusForbidden, "Collection %s is scoped and can't be watched", collName)
+		return
+	}
+
+	// Let the filter interceptor reject the stream, a filter it adds can't be applied to change events
+	filter, ok := s.interceptFilter(ctx, dbName, collName, bson.M{})
+	if !ok {
+		return
+	}
+	if len(filter) > 0 {
+		s.sendError(ctx, http.StatusForbidden, "Collection %s is filtered by the server and can't be watched", collName)
+		return
+	}
+
+	// Reserve a change stream, each one holds a connection
+	if !s.acquireChangeStream() {
+		ctx.Header("Retry-After", strconv.Itoa(changeStreamRetryAfter))
+		s.sendError(ctx, http.StatusServiceUnavailable, "Max number of change streams are open, try again later")
+		return
+	}
+	defer s.releaseChangeStream()
+
+	// Get collection handle with the request read preference
+	coll, ok := s.getCollection(ctx, dbName, collName)
+	if !ok {
+		return
+	}
+
+	// Register the stream so the reaper can stop it if the client stops reading
+	streamCtx, cancel := context.WithCancel(ctx.Request.Context())
+	defer cancel()
+	watched := s.cursors.addStream(cancel)
+	defer s.cursors.removeStream(watched)
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup).SetMaxAwaitTime(changeStreamMaxAwait)
+	stream, err := coll.Watch(streamCtx, mongo.Pipeline{}, opts)
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error opening change stream: %s", err.Error())
+		return
+	}
+	defer stream.Close(context.Background())
+
+	keepalive := changeStreamKeepalive
+	if s.cursors.ttl/2 < keepalive {
+		keepalive = s.cursors.ttl / 2
+	}
+
+	ctx.Header("Cache-Control", "no-cache")
+	lastSend := time.Now()
+	ctx.Stream(func(w io.Writer) bool {
+		// Each try waits up to the max await time for an event, the stream ends when it fails or is canceled
+		for !stream.TryNext(streamCtx) {
+			if stream.Err() != nil || stream.ID() == 0 {
+				return false
+			}
+
+			if time.Since(lastSend) >= keepalive {
+				if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+					return false
+				}
+				lastSend = time.Now()
+				s.cursors.touchStream(watched)
+				return true
+			}
+		}
+
+		var event bson.D
+		if err := stream.Decode(&event); err != nil {
+			return false
+		}
+		s.encodeChangeEvent(event)
+
+		ctx.SSEvent("change", s.jsonValue(event))
+		lastSend = time.Now()
+		s.cursors.touchStream(watched)
+		return true
+	})
+}
+
+// Reserves a change stream and returns true, or returns false if the max number of change streams are open
+func (s *server) acquireChangeStream() bool {
+
+	active := atomic.AddInt32(&s.activeChangeStreams, 1)
+	if s.maxChangeStreams > 0 && active > int32(s.maxChangeStreams) {
+		atomic.AddInt32(&s.activeChangeStreams, -1)
+		return false
+	}
+
+	return true
+}
+
+// Releases a change stream reserved by acquireChangeStream
+func (s *server) releaseChangeStream() {
+	atomic.AddInt32(&s.activeChangeStreams, -1)
+}
@@ -0,0 +1,429 @@
+package gomongoapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Server error code for duplicate key errors
+const duplicateKeyCode = 11000
+
+// Returns true if write routes are enabled, otherwise sends a forbidden response and returns false
+func (s *server) checkWritesEnabled(ctx *gin.Context) bool {
+	if !s.writesEnabled {
+		s.sendError(ctx, http.StatusForbidden, "Write routes are not enabled on this server")
+		return false
+	}
+
+	return true
+}
+
+// Inserts a document into the collection. /collections/:name/insert
+// Valid URL parameter is 'database'
+// The document is stamped with the fields of the tenant of the request, if requests are scoped to a tenant
+// Request body should be the document to insert
+//	ex) Request Body: {"UserName": "Jon"}
+func (s *server) collectionInsert(ctx *gin.Context) {
+
+	if !s.checkWritesEnabled(ctx) {
+		return
+	}
+
+	// Get database name, return error if one isn't available
+	dbName, ok := s.getDatabaseName(ctx)
+	if !ok {
+		return
+	}
+
+	// Get collection name, return error if one isn't passed or allowed
+	collName, ok := s.getCollectionName(ctx)
+	if !ok {
+		return
+	}
+
+	// Get document from request body
+	var doc bson.M
+	err := ctx.ShouldBindJSON(&doc)
+	if err != nil {
+		s.sendError(ctx, http.StatusBadRequest, "Error reading body request: %s", err.Error())
+		return
+	}
+
+	// Run the collection write validator
+	if validator, ok := s.writeValidators[collName]; ok {
+		if err := validator(doc); err != nil {
+			s.sendError(ctx, http.StatusUnprocessableEntity, "Document failed validation: %s", err.Error())
+			return
+		}
+	}
+
+	// Stamp the document with the tenant of the request so only that tenant can read it
+	tenant, ok := s.tenantFields(ctx)
+	if !ok {
+		return
+	}
+	for field, value := range tenant {
+		doc[field] = value
+	}
+
+	res, err := s.mongoClient.Database(dbName).Collection(collName).InsertOne(ctx.Request.Context(), doc)
+	if wcErr := writeConcernFailure(err); wcErr != nil && res != nil {
+		s.sendResult(ctx, http.StatusAccepted, bson.M{
+			"InsertedID":        res.InsertedID,
+			"WriteConcernError": writeConcernDetails(wcErr),
+		})
+		return
+	}
+	if err != nil {
+		var writeErr mongo.WriteException
+		if errors.As(err, &writeErr) && len(writeErr.WriteErrors) > 0 && writeErr.WriteErrors[0].Code == duplicateKeyCode {
+			s.sendErrorDetails(ctx, http.StatusConflict, "Duplicate key error", bson.M{
+				"Failed": []bson.M{writeErrorDetails(writeErr.WriteErrors[0])},
+			})
+			return
+		}
+
+		s.sendError(ctx, http.StatusInternalServerError, "Error running insert: %s", err.Error())
+		return
+	}
+
+	s.sendResult(ctx, http.StatusCreated, bson.M{"InsertedID": res.InsertedID})
+}
+
+// Inserts multiple documents into the collection. /collections/:name/insertMany
+// Valid URL parameters are 'database' and 'ordered'.
+// When ordered=false, documents that don't conflict are still inserted and the failed ones are reported.
+// Request body should be an array of documents to insert
+//	ex) Request Body: [{"UserName": "Jon"}, {"UserName": "Amy"}]
+func (s *server) collectionInsertMany(ctx *gin.Context) {
+
+	if !s.checkWritesEnabled(ctx) {
+		return
+	}
+
+	// Get database name, return error if one isn't available
+	dbName, ok := s.getDatabaseName(ctx)
+	if !ok {
+		return
+	}
+
+	// Get collection name, return error if one isn't passed or allowed
+	collName, ok := s.getCollectionName(ctx)
+	if !ok {
+		return
+	}
+
+	// Get ordered, default is true like the driver
+	ordered, err := strconv.ParseBool(ctx.DefaultQuery("ordered", "true"))
+	if err != nil {
+		s.sendError(ctx, http.StatusBadRequest, "Ordered is not a bool: %s", err.Error())
+		return
+	}
+
+	// Get documents from request body
+	var docs []bson.M
+	err = ctx.ShouldBindJSON(&docs)
+	if err != nil {
+		s.sendError(ctx, http.StatusBadRequest, "Error reading body request: %s", err.Error())
+		return
+	}
+	if len(docs) == 0 {
+		s.sendError(ctx, http.StatusBadRequest, "No documents were passed")
+		return
+	}
+
+	// Run the collection write validator on each document
+	if validator, ok := s.writeValidators[collName]; ok {
+		for i, doc := range docs {
+			if err := validator(doc); err != nil {
+				s.sendError(ctx, http.StatusUnprocessableEntity, "Document %d failed validation: %s", i, err.Error())
+				return
+			}
+		}
+	}
+
+	// Stamp the documents with the tenant of the request so only that tenant can read them
+	tenant, ok := s.tenantFields(ctx)
+	if !ok {
+		return
+	}
+
+	insertDocs := make([]interface{}, len(docs))
+	for i := range docs {
+		for field, value := range tenant {
+			docs[i][field] = value
+		}
+		insertDocs[i] = docs[i]
+	}
+
+	opts := options.InsertMany()
+	opts.SetOrdered(ordered)
+
+	res, err := s.mongoClient.Database(dbName).Collection(collName).InsertMany(ctx.Request.Context(), insertDocs, opts)
+	if wcErr := writeConcernFailure(err); wcErr != nil && res != nil {
+		s.sendResult(ctx, http.StatusAccepted, bson.M{
+			"InsertedIDs":       res.InsertedIDs,
+			"WriteConcernError": writeConcernDetails(wcErr),
+		})
+		return
+	}
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+			s.sendError(ctx, http.StatusInternalServerError, "Error running insert many: %s", err.Error())
+			return
+		}
+
+		// Report the documents that failed and the ones that were inserted
+		failedIndexes := make(map[int]bool, len(bulkErr.WriteErrors))
+		failed := make([]bson.M, len(bulkErr.WriteErrors))
+		allDuplicates := true
+		for i, writeErr := range bulkErr.WriteErrors {
+			failedIndexes[writeErr.Index] = true
+			failed[i] = writeErrorDetails(writeErr.WriteError)
+			if writeErr.Code != duplicateKeyCode {
+				allDuplicates = false
+			}
+		}
+
+		// Ordered inserts stop at the first failure
+		insertedIDs := []interface{}{}
+		for i, id := range res.InsertedIDs {
+			if ordered && i >= bulkErr.WriteErrors[0].Index {
+				break
+			}
+			if !failedIndexes[i] {
+				insertedIDs = append(insertedIDs, id)
+			}
+		}
+
+		code := http.StatusInternalServerError
+		message := "Error running insert many"
+		if allDuplicates {
+			code = http.StatusConflict
+			message = "Duplicate key error"
+		}
+
+		s.sendErrorDetails(ctx, code, message, bson.M{
+			"InsertedIDs": insertedIDs,
+			"Failed":      failed,
+		})
+		return
+	}
+
+	s.sendResult(ctx, http.StatusCreated, bson.M{"InsertedIDs": res.InsertedIDs})
+}
+
+// Returns the details of a write error.
+// For duplicate key errors the conflicting key pattern and value are included.
+func writeErrorDetails(writeErr mongo.WriteError) bson.M {
+
+	details := bson.M{
+		"Index":   writeErr.Index,
+		"Code":    writeErr.Code,
+		"Message": writeErr.Message,
+	}
+
+	if writeErr.Code == duplicateKeyCode && writeErr.Raw != nil {
+		var keyValue bson.M
+		if err := writeErr.Raw.Lookup("keyValue").Unmarshal(&keyValue); err == nil {
+			details["Key"] = keyValue
+		}
+	}
+
+	return details
+}
+
+// Returns the write concern error if the write was applied but its write concern wasn't satisfied, otherwise nil.
+// Writes with a write concern error are reported with 202, the data was written but its durability isn't confirmed.
+func writeConcernFailure(err error) *mongo.WriteConcernError {
+
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) && len(writeErr.WriteErrors) == 0 {
+		return writeErr.WriteConcernError
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) == 0 {
+		return bulkErr.WriteConcernError
+	}
+
+	return nil
+}
+
+// Returns the details of a write concern error
+func writeConcernDetails(wcErr *mongo.WriteConcernError) bson.M {
+	return bson.M{
+		"Code":    wcErr.Code,
+		"Message": wcErr.Message,
+	}
+}
+
+// Request body of the upsert route
+type upsertBody struct {
+	Filter bson.M
+	Update bson.M
+}
+
+// Runs a find one and update with upsert on the collection. /collections/:name/upsert
+// Valid URL parameter is 'database'
+// Request body should have the filter and update, the resulting document is returned
+// The filter is validated and scoped like reads and an inserted document is stamped with the tenant of the request
+// The document is encoded like read results, including id handling
+//	ex) Request Body: {"filter": {"Name": "visits"}, "update": {"$inc": {"Count": 1}}}
+func (s *server) collectionUpsert(ctx *gin.Context) {
+
+	if !s.checkWritesEnabled(ctx) {
+		return
+	}
+
+	// Get database name, return error if one isn't available
+	dbName, ok := s.getDatabaseName(ctx)
+	if !ok {
+		return
+	}
+
+	// Get collection name, return error if one isn't passed or allowed
+	collName, ok := s.getCollectionName(ctx)
+	if !ok {
+		return
+	}
+
+	// Time series collections don't support upserts
+	if s.collectionTimeField(ctx.Request.Context(), dbName, collName) != "" {
+		s.sendError(ctx, http.StatusBadRequest, "Upsert is not supported on time series collection %s", collName)
+		return
+	}
+
+	// Get filter and update from request body
+	var body upsertBody
+	err := ctx.ShouldBindJSON(&body)
+	if err != nil {
+		s.sendError(ctx, http.StatusBadRequest, "Error reading body request: %s", err.Error())
+		return
+	}
+	s.setAuditQuery(ctx, body.Filter)
+	if body.Filter == nil {
+		body.Filter = bson.M{}
+	}
+	if !s.checkFilter(ctx, body.Filter) {
+		return
+	}
+	if len(body.Update) == 0 {
+		s.sendError(ctx, http.StatusBadRequest, "Update was not passed, one is needed")
+		return
+	}
+
+	// Run the collection write validator on the update
+	if validator, ok := s.writeValidators[collName]; ok {
+		if err := validator(body.Update); err != nil {
+			s.sendError(ctx, http.StatusUnprocessableEntity, "Update failed validation: %s", err.Error())
+			return
+		}
+	}
+
+	// Scope the filter to the collection base filter and the tenant of the request, the update can't change the tenant
+	tenant, ok := s.tenantFields(ctx)
+	if !ok {
+		return
+	}
+	if field := updatedTenantField(body.Update, tenant); field != "" {
+		s.sendError(ctx, http.StatusForbidden, "Update can't change tenant field %s", field)
+		return
+	}
+	body.Filter, ok = s.scopeFilter(ctx, collName, body.Filter)
+	if !ok {
+		return
+	}
+
+	// Stamp an inserted document with the tenant of the request
+	if len(tenant) > 0 {
+		setOnInsert, isDoc := body.Update["$setOnInsert"].(map[string]interface{})
+		if !isDoc {
+			setOnInsert = make(map[string]interface{}, len(tenant))
+		}
+		for field, value := range tenant {
+			setOnInsert[field] = value
+		}
+		body.Update["$setOnInsert"] = setOnInsert
+	}
+
+	opts := options.FindOneAndUpdate()
+	opts.SetUpsert(true)
+	opts.SetReturnDocument(options.After)
+
+	var res bson.D
+	err = s.mongoClient.Database(dbName).Collection(collName).FindOneAndUpdate(ctx.Request.Context(), body.Filter, body.Update, opts).Decode(&res)
+	if wcErr := writeConcernFailure(err); wcErr != nil {
+		s.sendResult(ctx, http.StatusAccepted, bson.M{"WriteConcernError": writeConcernDetails(wcErr)})
+		return
+	}
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error running upsert: %s", err.Error())
+		return
+	}
+	docs := []bson.D{res}
+	s.encodeResults(docs)
+	s.transformResults(ctx, docs)
+
+	s.sendResult(ctx, http.StatusOK, s.jsonValue(docs[0]))
+}
+
+// Drops the collection. /collections/:name/drop
+// Valid URL parameters are 'database' and 'confirm', confirm must match the collection name.
+// Requires both writes and destructive operations to be enabled.
+func (s *server) collectionDrop(ctx *gin.Context) {
+
+	if !s.checkWritesEnabled(ctx) {
+		return
+	}
+	if !s.destructiveOpsEnabled {
+		s.sendError(ctx, http.StatusForbidden, "Destructive operations are not enabled on this server")
+		return
+	}
+
+	// Get database name, return error if one isn't available
+	dbName, ok := s.getDatabaseName(ctx)
+	if !ok {
+		return
+	}
+
+	// Get collection name, return error if one isn't passed or allowed
+	collName, ok := s.getCollectionName(ctx)
+	if !ok {
+		return
+	}
+
+	// Collections are shared by every tenant, so a tenant can't drop one
+	if s.tenantFilter != nil {
+		s.sendError(ctx, http.StatusForbidden, "Drop is not allowed when requests are scoped to a tenant")
+		return
+	}
+
+	// Ensure the drop was confirmed for this collection
+	if ctx.Query("confirm") != collName {
+		s.sendError(ctx, http.StatusBadRequest, "Url param 'confirm' must match the collection name")
+		return
+	}
+
+	err := s.mongoClient.Database(dbName).Collection(collName).Drop(ctx.Request.Context())
+	if wcErr := writeConcernFailure(err); wcErr != nil {
+		s.sendResult(ctx, http.StatusAccepted, bson.M{
+			"Dropped":           collName,
+			"WriteConcernError": writeConcernDetails(wcErr),
+		})
+		return
+	}
+	if err != nil {
+		s.sendError(ctx, http.StatusInternalServerError, "Error running drop: %s", err.Error())
+		return
+	}
+
+	s.sendResult(ctx, http.StatusOK, bson.M{"Dropped": collName})
+}
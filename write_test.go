@@ -0,0 +1,87 @@
+package gomongoapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTenantWrites(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		tenant bson.M
+		path   string
+		body   string
+	}{
+		{"insert with operator tenant", bson.M{"TenantID": bson.M{"$in": bson.A{"a", "b"}}}, "/api/collections/users/insert", `{"Name": "Jon"}`},
+		{"insertMany with operator tenant", bson.M{"$or": bson.A{bson.M{"TenantID": "a"}}}, "/api/collections/users/insertMany", `[{"Name": "Jon"}]`},
+		{"upsert setting tenant", bson.M{"TenantID": "a"}, "/api/collections/users/upsert", `{"Filter": {"Name": "Jon"}, "Update": {"$set": {"TenantID": "b"}}}`},
+		{"upsert setting tenant parent", bson.M{"Owner.TenantID": "a"}, "/api/collections/users/upsert", `{"Filter": {}, "Update": {"$set": {"Owner": {}}}}`},
+		{"upsert renaming to tenant", bson.M{"TenantID": "a"}, "/api/collections/users/upsert", `{"Filter": {}, "Update": {"$rename": {"Other": "TenantID"}}}`},
+		{"drop", bson.M{"TenantID": "a"}, "/api/collections/users/drop?confirm=users", ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := ServerOptions()
+			opts.SetDefaultDB("app")
+			opts.SetWritesEnabled(true)
+			opts.SetDestructiveOpsEnabled(true)
+			opts.SetTenantFilter(func(ctx *gin.Context) bson.M {
+				return tt.tenant
+			})
+			s := newTestServer(opts)
+
+			rec := serveTest(s, http.MethodPost, tt.path, tt.body)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdatedTenantField(t *testing.T) {
+
+	tenant := bson.M{"TenantID": "a"}
+	update := bson.M{"$set": map[string]interface{}{"Name": "Jon"}, "$inc": map[string]interface{}{"Visits": 1}}
+	if field := updatedTenantField(update, tenant); field != "" {
+		t.Errorf("expected update not to change the tenant, got %s", field)
+	}
+
+	update = bson.M{"$unset": map[string]interface{}{"TenantID.Sub": ""}}
+	if field := updatedTenantField(update, tenant); field != "TenantID" {
+		t.Errorf("expected update to change TenantID, got %q", field)
+	}
+}
+
+func TestUpsertChecksFilter(t *testing.T) {
+
+	opts := ServerOptions()
+	opts.SetDefaultDB("app")
+	opts.SetWritesEnabled(true)
+	opts.SetDisallowRegex(true)
+	opts.SetValidateOperators(true)
+	opts.SetMaxFilterDepth(2)
+	s := newTestServer(opts)
+
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{"regex", `{"Name": {"$regex": "^J"}}`},
+		{"invalid operator", `{"Name": {"$in": 1}}`},
+		{"too deep", `{"a": {"b": {"c": {"d": 1}}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveTest(s, http.MethodPost, "/api/collections/users/upsert", `{"Filter": `+tt.filter+`, "Update": {"$inc": {"Count": 1}}}`)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}